@@ -10,6 +10,8 @@ import (
 )
 
 func main() {
+	config.Parse()
+
 	if config.CredentialsFlow == config.CredentialsFlowRedirect {
 		client, exists, err := auth.CachedRedirect(
 			config.ClientID,
@@ -1,9 +1,20 @@
 module github.com/kristofferostlund/spot
 
+go 1.27.1
+
 require (
 	github.com/satori/go.uuid v1.2.0
 	github.com/sirupsen/logrus v1.1.0
 	github.com/zmb3/spotify v0.0.0-20180925143944-a4bd83f60e06
-	golang.org/x/net v0.0.0-20181005035420-146acd28ed58 // indirect
 	golang.org/x/oauth2 v0.0.0-20181003184128-c57b0facaced
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v0.0.0-20180402223658-b729f2633dfe // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/testify v1.2.2 // indirect
+	golang.org/x/crypto v0.0.0-20180904163835-0709b304e793 // indirect
+	golang.org/x/net v0.0.0-20181005035420-146acd28ed58 // indirect
+	golang.org/x/sys v0.0.0-20180905080454-ebe1bf3edb33 // indirect
+)
@@ -0,0 +1,202 @@
+package artistcrawl
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/fullalbum"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+// trackChunkSize is the number of tracks fetched per request
+const trackChunkSize = 50
+
+type Reached struct {
+	Artist spotify.SimpleArtist
+	Path   []string
+	Depth  int
+}
+
+type Result struct {
+	Tracks []spotify.FullTrack
+	Paths  map[spotify.ID][]string
+}
+
+type budget struct {
+	used int
+	max  int
+}
+
+func (b *budget) take() bool {
+	if b.used >= b.max {
+		return false
+	}
+
+	b.used++
+
+	return true
+}
+
+func (b *budget) isExhausted() bool {
+	return b.used >= b.max
+}
+
+// Crawl walks the related artists graph outward from the most frequent library
+// artists, and returns tracks by the artists not in the library it reached,
+// along with the path of artist names leading to each track.
+func Crawl(client spotify.Client, library libraryindex.Index) (Result, error) {
+	result := Result{Tracks: []spotify.FullTrack{}, Paths: map[spotify.ID][]string{}}
+	requests := &budget{max: config.CrawlRequestBudget}
+
+	reached, err := walk(client, library, requests)
+	if err != nil {
+		return result, err
+	}
+
+	logrus.Infof("Reached %d new artist(s) using %d/%d request(s)", len(reached), requests.used, requests.max)
+
+	for index, artist := range reached {
+		if requests.isExhausted() {
+			logrus.Infof("Exhausted the request budget, skipping the tracks of %d artist(s)", len(reached)-index)
+
+			break
+		}
+
+		tracks, err := getTracks(client, artist.Artist, requests)
+		if err != nil {
+			return result, err
+		}
+
+		for _, track := range tracks {
+			if _, exists := result.Paths[track.ID]; exists {
+				continue
+			}
+
+			result.Paths[track.ID] = artist.Path
+			result.Tracks = append(result.Tracks, track)
+		}
+	}
+
+	logrus.Infof("Fetched %d track(s) using %d/%d request(s) in total", len(result.Tracks), requests.used, requests.max)
+
+	return result, nil
+}
+
+func walk(client spotify.Client, library libraryindex.Index, requests *budget) ([]Reached, error) {
+	reached := []Reached{}
+	queue := []Reached{}
+	isVisited := map[spotify.ID]bool{}
+
+	for _, artistCount := range library.GetArtistsByTrackCount() {
+		if len(queue) == config.CrawlSeedArtistCount {
+			break
+		}
+
+		if artistCount.Artist.ID == "" {
+			continue
+		}
+
+		isVisited[artistCount.Artist.ID] = true
+		queue = append(queue, Reached{Artist: artistCount.Artist, Path: []string{artistCount.Artist.Name}})
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current.Depth >= config.CrawlDepth {
+			continue
+		}
+
+		if !requests.take() {
+			logrus.Infof("Exhausted the request budget at depth %d", current.Depth)
+
+			break
+		}
+
+		relatedArtists, err := client.GetRelatedArtists(current.Artist.ID)
+		if err != nil {
+			return reached, fmt.Errorf("Failed to get related artists of %s: %v", current.Artist.Name, err)
+		}
+
+		for _, related := range relatedArtists {
+			if isVisited[related.ID] {
+				continue
+			}
+
+			isVisited[related.ID] = true
+
+			if len(library.GetTracksByArtist(related.SimpleArtist)) > 0 {
+				continue
+			}
+
+			next := Reached{
+				Artist: related.SimpleArtist,
+				Path:   append(append([]string{}, current.Path...), related.Name),
+				Depth:  current.Depth + 1,
+			}
+
+			reached = append(reached, next)
+			queue = append(queue, next)
+		}
+	}
+
+	return reached, nil
+}
+
+// getTracks gets the tracks of the artist, taking each request it makes from
+// the budget.
+func getTracks(client spotify.Client, artist spotify.SimpleArtist, requests *budget) ([]spotify.FullTrack, error) {
+	tracks := []spotify.FullTrack{}
+
+	if config.CrawlTrackSource == config.CrawlTrackSourceLatest {
+		album, exists, err := fullalbum.GetLatestRelease(client, artist.ID, requests.take)
+		if err != nil || !exists {
+			return tracks, err
+		}
+
+		ids := []spotify.ID{}
+		for _, track := range album.Tracks.Tracks {
+			ids = append(ids, track.ID)
+		}
+
+		if len(ids) > config.CrawlTracksPerArtist {
+			ids = ids[:config.CrawlTracksPerArtist]
+		}
+
+		for _, chunk := range utils.ChunkIDs(ids, trackChunkSize) {
+			if len(chunk) == 0 || !requests.take() {
+				break
+			}
+
+			chunkTracks, err := fulltrack.GetMany(client, chunk)
+			if err != nil {
+				return tracks, err
+			}
+
+			tracks = append(tracks, chunkTracks...)
+		}
+	} else {
+		if !requests.take() {
+			return tracks, nil
+		}
+
+		topTracks, err := client.GetArtistsTopTracks(artist.ID, config.Country)
+		if err != nil {
+			return tracks, fmt.Errorf("Failed to get top tracks of %s: %v", artist.Name, err)
+		}
+
+		tracks = topTracks
+	}
+
+	if len(tracks) > config.CrawlTracksPerArtist {
+		tracks = tracks[:config.CrawlTracksPerArtist]
+	}
+
+	return tracks, nil
+}
@@ -0,0 +1,177 @@
+package artistgenre
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type Profile map[string]float64
+
+var (
+	genreCache       = map[spotify.ID][]string{}
+	isGenreCacheRead = false
+)
+
+func GetMany(client spotify.Client, artistIDs []spotify.ID) (map[spotify.ID][]string, error) {
+	genres := map[spotify.ID][]string{}
+	uncachedArtistIDs := []spotify.ID{}
+
+	if err := readCache(); err != nil {
+		return genres, err
+	}
+
+	for _, id := range artistIDs {
+		if artistGenres, exists := genreCache[id]; exists {
+			genres[id] = artistGenres
+
+			continue
+		}
+
+		if _, isListed := genres[id]; !isListed && id != "" {
+			genres[id] = []string{}
+			uncachedArtistIDs = append(uncachedArtistIDs, id)
+		}
+	}
+
+	if len(uncachedArtistIDs) == 0 {
+		return genres, nil
+	}
+
+	for _, chunk := range utils.ChunkIDs(uncachedArtistIDs, config.ArtistChunkSize) {
+		artists, err := client.GetArtists(chunk...)
+		if err != nil {
+			return genres, fmt.Errorf("Failed to get %d artist(s): %v", len(chunk), err)
+		}
+
+		for _, artist := range artists {
+			if artist == nil {
+				continue
+			}
+
+			genres[artist.ID] = artist.Genres
+			genreCache[artist.ID] = artist.Genres
+		}
+	}
+
+	if err := cache.WriteCache(config.ArtistGenreCacheFilename, genreCache); err != nil {
+		return genres, err
+	}
+
+	return genres, nil
+}
+
+func GetArtistIDs(tracks []spotify.FullTrack) []spotify.ID {
+	ids := []spotify.ID{}
+	isListed := map[spotify.ID]bool{}
+
+	for _, track := range tracks {
+		for _, artist := range track.Artists {
+			if !isListed[artist.ID] {
+				isListed[artist.ID] = true
+				ids = append(ids, artist.ID)
+			}
+		}
+	}
+
+	return ids
+}
+
+func GetTrackGenres(track spotify.FullTrack, genres map[spotify.ID][]string) []string {
+	trackGenres := []string{}
+	isListed := map[string]bool{}
+
+	for _, artist := range track.Artists {
+		for _, genre := range genres[artist.ID] {
+			if !isListed[genre] {
+				isListed[genre] = true
+				trackGenres = append(trackGenres, genre)
+			}
+		}
+	}
+
+	return trackGenres
+}
+
+func CreateProfile(tracks []spotify.FullTrack, genres map[spotify.ID][]string) Profile {
+	profile := Profile{}
+	maxWeight := 0.0
+
+	for _, track := range tracks {
+		trackGenres := GetTrackGenres(track, genres)
+
+		for _, genre := range trackGenres {
+			profile[genre] += 1 / float64(len(trackGenres))
+
+			if profile[genre] > maxWeight {
+				maxWeight = profile[genre]
+			}
+		}
+	}
+
+	// Weights are relative to the most common genre of the library
+	for genre, weight := range profile {
+		profile[genre] = weight / maxWeight
+	}
+
+	return profile
+}
+
+func (p Profile) GetOverlap(genres []string) float64 {
+	if len(genres) == 0 {
+		return 0
+	}
+
+	total := 0.0
+
+	for _, genre := range genres {
+		total += p[genre]
+	}
+
+	return total / float64(len(genres))
+}
+
+func IsBlocked(genres []string, blocklist []string) (string, bool) {
+	for _, genre := range genres {
+		for _, blocked := range blocklist {
+			if matchesTerm(genre, blocked) {
+				return genre, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func readCache() error {
+	if isGenreCacheRead {
+		return nil
+	}
+
+	if err := cache.ReadCache(config.ArtistGenreCacheFilename, &genreCache); err != nil {
+		return err
+	}
+
+	isGenreCacheRead = true
+
+	return nil
+}
+
+func matchesTerm(genre, term string) bool {
+	genre = strings.ToLower(genre)
+	term = strings.ToLower(strings.TrimSpace(term))
+
+	if term == "" {
+		return false
+	}
+
+	return genre == term ||
+		strings.HasPrefix(genre, term+" ") ||
+		strings.HasSuffix(genre, " "+term) ||
+		strings.Contains(genre, " "+term+" ")
+}
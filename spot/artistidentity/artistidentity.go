@@ -0,0 +1,127 @@
+package artistidentity
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+)
+
+// AliasTable maps a canonical artist ID to the other IDs and spellings
+// Spotify lists the same artist under.
+type AliasTable map[string][]string
+
+var (
+	aliases          = map[string]string{}
+	isAliasCacheRead = false
+)
+
+func GetKey(artist spotify.SimpleArtist) string {
+	readAliases()
+
+	if canonical, exists := aliases[string(artist.ID)]; exists {
+		return canonical
+	}
+
+	name := normalizeName(artist.Name)
+
+	if canonical, exists := aliases[name]; exists {
+		return canonical
+	}
+
+	if artist.ID != "" {
+		return string(artist.ID)
+	}
+
+	return name
+}
+
+func GetKeys(artists []spotify.SimpleArtist) []string {
+	keys := []string{}
+	isListed := map[string]bool{}
+
+	for _, artist := range artists {
+		key := GetKey(artist)
+
+		if !isListed[key] {
+			isListed[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func CombineAffinity(counts []int) int {
+	values := []float64{}
+
+	for _, count := range counts {
+		values = append(values, float64(count))
+	}
+
+	return int(CombineAffinityValues(values))
+}
+
+// CombineAffinityValues combines the affinities of the credited artists as
+// configured, like CombineAffinity but for fractional affinities.
+func CombineAffinityValues(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	max := 0.0
+
+	for _, value := range values {
+		total += value
+
+		if value > max {
+			max = value
+		}
+	}
+
+	switch config.ArtistAffinityCombination {
+	case config.ArtistAffinitySum:
+		return total
+	case config.ArtistAffinityMean:
+		return total / float64(len(values))
+	case config.ArtistAffinityFirst:
+		return values[0]
+	default:
+		return max
+	}
+}
+
+func readAliases() {
+	if isAliasCacheRead {
+		return
+	}
+
+	// Set up front, as keys are still usable without aliases and a missing or
+	// broken file shouldn't be warned about on every lookup
+	isAliasCacheRead = true
+
+	table := AliasTable{}
+
+	if err := cache.ReadCache(config.ArtistAliasFilename, &table); err != nil {
+		logrus.Warnf("Failed to read artist aliases: %v", err)
+
+		return
+	}
+
+	for canonical, names := range table {
+		aliases[canonical] = canonical
+
+		for _, name := range names {
+			aliases[name] = canonical
+			aliases[normalizeName(name)] = canonical
+		}
+	}
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
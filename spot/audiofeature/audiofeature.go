@@ -0,0 +1,175 @@
+package audiofeature
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+const (
+	Acousticness     = "acousticness"
+	Danceability     = "danceability"
+	Energy           = "energy"
+	Instrumentalness = "instrumentalness"
+	Liveness         = "liveness"
+	Speechiness      = "speechiness"
+	Valence          = "valence"
+)
+
+type Statistic struct {
+	Mean   float64
+	Spread float64
+}
+
+type Profile map[string]Statistic
+
+var (
+	FeatureNames = []string{
+		Acousticness,
+		Danceability,
+		Energy,
+		Instrumentalness,
+		Liveness,
+		Speechiness,
+		Valence,
+	}
+
+	featureCache       = map[spotify.ID]spotify.AudioFeatures{}
+	isFeatureCacheRead = false
+)
+
+func GetMany(client spotify.Client, trackIDs []spotify.ID) (map[spotify.ID]spotify.AudioFeatures, error) {
+	features := map[spotify.ID]spotify.AudioFeatures{}
+	uncachedTrackIDs := []spotify.ID{}
+	isListed := map[spotify.ID]bool{}
+
+	if err := readCache(); err != nil {
+		return features, err
+	}
+
+	for _, id := range trackIDs {
+		if feature, exists := featureCache[id]; exists {
+			features[id] = feature
+
+			continue
+		}
+
+		if !isListed[id] && id != "" {
+			isListed[id] = true
+			uncachedTrackIDs = append(uncachedTrackIDs, id)
+		}
+	}
+
+	if len(uncachedTrackIDs) == 0 {
+		return features, nil
+	}
+
+	for _, chunk := range utils.ChunkIDs(uncachedTrackIDs, config.AudioFeatureChunkSize) {
+		chunkFeatures, err := client.GetAudioFeatures(chunk...)
+		if err != nil {
+			return features, fmt.Errorf("Failed to get audio features of %d track(s): %v", len(chunk), err)
+		}
+
+		for _, feature := range chunkFeatures {
+			if feature == nil {
+				continue
+			}
+
+			features[feature.ID] = *feature
+			featureCache[feature.ID] = *feature
+		}
+	}
+
+	if err := cache.WriteCache(config.AudioFeatureCacheFilename, featureCache); err != nil {
+		return features, err
+	}
+
+	return features, nil
+}
+
+func GetValue(feature spotify.AudioFeatures, name string) float64 {
+	switch name {
+	case Acousticness:
+		return float64(feature.Acousticness)
+	case Danceability:
+		return float64(feature.Danceability)
+	case Energy:
+		return float64(feature.Energy)
+	case Instrumentalness:
+		return float64(feature.Instrumentalness)
+	case Liveness:
+		return float64(feature.Liveness)
+	case Speechiness:
+		return float64(feature.Speechiness)
+	case Valence:
+		return float64(feature.Valence)
+	default:
+		return 0
+	}
+}
+
+func CreateProfile(features []spotify.AudioFeatures) Profile {
+	profile := Profile{}
+
+	if len(features) == 0 {
+		return profile
+	}
+
+	for _, name := range FeatureNames {
+		values := []float64{}
+
+		for _, feature := range features {
+			values = append(values, GetValue(feature, name))
+		}
+
+		mean := utils.AverageFloat(values)
+		squaredDiffs := []float64{}
+
+		for _, value := range values {
+			squaredDiffs = append(squaredDiffs, math.Pow(value-mean, 2))
+		}
+
+		profile[name] = Statistic{
+			Mean:   mean,
+			Spread: math.Sqrt(utils.AverageFloat(squaredDiffs)),
+		}
+	}
+
+	return profile
+}
+
+func (p Profile) GetDistance(feature spotify.AudioFeatures) float64 {
+	if len(p) == 0 {
+		return 0
+	}
+
+	distances := []float64{}
+
+	for _, name := range FeatureNames {
+		statistic := p[name]
+		spread := math.Max(statistic.Spread, config.AudioFeatureMinSpread)
+
+		distances = append(distances, math.Abs(GetValue(feature, name)-statistic.Mean)/spread)
+	}
+
+	return utils.AverageFloat(distances)
+}
+
+func readCache() error {
+	if isFeatureCacheRead {
+		return nil
+	}
+
+	if err := cache.ReadCache(config.AudioFeatureCacheFilename, &featureCache); err != nil {
+		return err
+	}
+
+	isFeatureCacheRead = true
+
+	return nil
+}
@@ -50,6 +50,8 @@ func getAuthenticator(redirectURL string) spotify.Authenticator {
 		spotify.ScopeUserTopRead,
 		spotify.ScopeUserReadCurrentlyPlaying,
 		spotify.ScopeUserReadPlaybackState,
+		spotify.ScopeUserLibraryRead,
+		spotify.ScopeUserReadRecentlyPlayed,
 	)
 }
 
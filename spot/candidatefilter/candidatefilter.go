@@ -0,0 +1,175 @@
+package candidatefilter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/filterlist"
+	"github.com/kristofferostlund/spot/spot/suggestionlog"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type Filter interface {
+	Name() string
+	Keep(track spotify.FullTrack, album spotify.FullAlbum) bool
+}
+
+// Preparer is implemented by filters needing to look at every candidate
+// before filtering, which allows them to fetch their data in batches.
+type Preparer interface {
+	Prepare(tracks []spotify.FullTrack) error
+}
+
+// Pipeline runs candidates through its filters in order and counts how many
+// candidates each filter dropped. Copies share the same counts.
+type Pipeline struct {
+	filters []Filter
+	dropped map[string]int
+}
+
+func CreatePipeline(filters ...Filter) Pipeline {
+	return Pipeline{
+		filters: filters,
+		dropped: map[string]int{},
+	}
+}
+
+// CreateDefaultPipeline sets up the filters configured by flags, followed by
+// the block lists and, when suppressing repeats, the suggestion history.
+func CreateDefaultPipeline(
+	client spotify.Client,
+	lists filterlist.Lists,
+	history suggestionlog.History,
+) Pipeline {
+	filters := []Filter{}
+
+	if config.MinDurationSeconds > 0 || config.MaxDurationSeconds > 0 {
+		filters = append(filters, duration{min: config.MinDurationSeconds, max: config.MaxDurationSeconds})
+	}
+
+	if config.ReleaseWindowDays > 0 {
+		filters = append(filters, releaseWindow{days: config.ReleaseWindowDays})
+	}
+
+	if config.ExplicitMode != config.ExplicitModeAny {
+		filters = append(filters, explicit{mode: config.ExplicitMode})
+	}
+
+	if config.MinAlbumSize > 0 {
+		filters = append(filters, albumSize{min: config.MinAlbumSize})
+	}
+
+	if config.MinPopularity > 0 || config.MaxPopularity < 100 {
+		filters = append(filters, popularity{min: config.MinPopularity, max: config.MaxPopularity})
+	}
+
+	if config.RequireMarket {
+		filters = append(filters, market{country: config.Country})
+	}
+
+	if len(config.AlbumTypes) > 0 {
+		filters = append(filters, createAlbumType(config.AlbumTypes))
+	}
+
+	filters = append(filters, blocklist{lists: lists})
+
+	if len(config.GenreBlocklist) > 0 {
+		filters = append(filters, genreBlocklist{
+			client:    client,
+			blocklist: config.GenreBlocklist,
+			genres:    map[spotify.ID][]string{},
+		})
+	}
+
+	if config.HistoryMode == config.HistoryModeSuppress {
+		filters = append(filters, suppressedHistory{history: history})
+	}
+
+	return CreatePipeline(filters...)
+}
+
+// With returns a copy of the pipeline with the filters added last, still
+// sharing the counts of the original.
+func (p Pipeline) With(filters ...Filter) Pipeline {
+	return Pipeline{filters: append(append([]Filter{}, p.filters...), filters...), dropped: p.dropped}
+}
+
+// Without returns a copy of the pipeline without the named filters, still
+// sharing the counts of the original.
+func (p Pipeline) Without(names ...string) Pipeline {
+	filters := []Filter{}
+
+	for _, filter := range p.filters {
+		if !utils.ContainsFold(names, filter.Name()) {
+			filters = append(filters, filter)
+		}
+	}
+
+	return Pipeline{filters: filters, dropped: p.dropped}
+}
+
+func (p Pipeline) Prepare(tracks []spotify.FullTrack) error {
+	for _, filter := range p.filters {
+		if preparer, ok := filter.(Preparer); ok {
+			if err := preparer.Prepare(tracks); err != nil {
+				return fmt.Errorf("Failed to prepare filter %s: %v", filter.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p Pipeline) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	for _, filter := range p.filters {
+		if !filter.Keep(track, album) {
+			logrus.Debugf(
+				"Dropping %s by %s, filtered by %s",
+				track.Name,
+				utils.JoinArtists(track.Artists, ", "),
+				filter.Name(),
+			)
+
+			p.dropped[filter.Name()]++
+
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p Pipeline) GetDroppedCount() int {
+	count := 0
+
+	for _, dropped := range p.dropped {
+		count += dropped
+	}
+
+	return count
+}
+
+func (p Pipeline) LogSummary() {
+	if p.GetDroppedCount() == 0 {
+		logrus.Info("No candidates were dropped by filters")
+
+		return
+	}
+
+	names := []string{}
+	for name := range p.dropped {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return p.dropped[names[i]] > p.dropped[names[j]]
+	})
+
+	for _, name := range names {
+		logrus.Infof("Dropped %d candidate(s) by filter %s", p.dropped[name], name)
+	}
+}
@@ -0,0 +1,225 @@
+package candidatefilter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/artistgenre"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/filterlist"
+	"github.com/kristofferostlund/spot/spot/suggestionlog"
+)
+
+const (
+	DurationFilterName       = "duration"
+	ReleaseWindowFilterName  = "release-window"
+	ReleaseYearFilterName    = "release-year"
+	ExplicitFilterName       = "explicit"
+	AlbumSizeFilterName      = "album-size"
+	PopularityFilterName     = "popularity"
+	MarketFilterName         = "market"
+	AlbumTypeFilterName      = "album-type"
+	BlocklistFilterName      = "blocklist"
+	HistoryFilterName        = "suggestion-history"
+	GenreBlocklistFilterName = "genre-blocklist"
+)
+
+type duration struct {
+	min int
+	max int
+}
+
+func (f duration) Name() string {
+	return DurationFilterName
+}
+
+func (f duration) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	seconds := track.Duration / 1000
+
+	if f.min > 0 && seconds < f.min {
+		return false
+	}
+
+	return f.max <= 0 || seconds <= f.max
+}
+
+type releaseWindow struct {
+	days int
+}
+
+func (f releaseWindow) Name() string {
+	return ReleaseWindowFilterName
+}
+
+func (f releaseWindow) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	return !album.ReleaseDateTime().Before(time.Now().AddDate(0, 0, -f.days))
+}
+
+type releaseYear struct {
+	min int
+}
+
+// CreateReleaseYear creates a filter dropping candidates released before the
+// given year.
+func CreateReleaseYear(min int) Filter {
+	return releaseYear{min: min}
+}
+
+func (f releaseYear) Name() string {
+	return ReleaseYearFilterName
+}
+
+func (f releaseYear) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	return album.ReleaseDateTime().Year() >= f.min
+}
+
+type explicit struct {
+	mode string
+}
+
+func (f explicit) Name() string {
+	return ExplicitFilterName
+}
+
+func (f explicit) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	switch f.mode {
+	case config.ExplicitModeExplicit:
+		return track.Explicit
+	case config.ExplicitModeClean:
+		return !track.Explicit
+	}
+
+	return true
+}
+
+type albumSize struct {
+	min int
+}
+
+func (f albumSize) Name() string {
+	return AlbumSizeFilterName
+}
+
+func (f albumSize) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	return album.Tracks.Total >= f.min
+}
+
+type popularity struct {
+	min int
+	max int
+}
+
+func (f popularity) Name() string {
+	return PopularityFilterName
+}
+
+func (f popularity) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	return track.Popularity >= f.min && track.Popularity <= f.max
+}
+
+type market struct {
+	country string
+}
+
+func (f market) Name() string {
+	return MarketFilterName
+}
+
+// Keep lets tracks without any listed markets through, as Spotify leaves them
+// out when the track was requested for a specific market.
+func (f market) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	if len(track.AvailableMarkets) == 0 {
+		return true
+	}
+
+	for _, available := range track.AvailableMarkets {
+		if strings.EqualFold(available, f.country) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type albumType struct {
+	allowed map[string]bool
+}
+
+func createAlbumType(types []string) albumType {
+	allowed := map[string]bool{}
+
+	for _, t := range types {
+		allowed[strings.ToLower(t)] = true
+	}
+
+	return albumType{allowed: allowed}
+}
+
+func (f albumType) Name() string {
+	return AlbumTypeFilterName
+}
+
+func (f albumType) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	return f.allowed[strings.ToLower(album.AlbumType)]
+}
+
+type blocklist struct {
+	lists filterlist.Lists
+}
+
+func (f blocklist) Name() string {
+	return BlocklistFilterName
+}
+
+func (f blocklist) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	_, blocked := f.lists.IsBlocked(track, album)
+
+	return !blocked
+}
+
+type genreBlocklist struct {
+	client    spotify.Client
+	blocklist []string
+	genres    map[spotify.ID][]string
+}
+
+func (f genreBlocklist) Name() string {
+	return GenreBlocklistFilterName
+}
+
+func (f genreBlocklist) Prepare(tracks []spotify.FullTrack) error {
+	genres, err := artistgenre.GetMany(f.client, artistgenre.GetArtistIDs(tracks))
+	if err != nil {
+		return err
+	}
+
+	for id, artistGenres := range genres {
+		f.genres[id] = artistGenres
+	}
+
+	return nil
+}
+
+func (f genreBlocklist) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	blockedGenre, isBlocked := artistgenre.IsBlocked(artistgenre.GetTrackGenres(track, f.genres), f.blocklist)
+	if isBlocked {
+		logrus.Debugf("%s is in the blocked genre %s", track.Name, blockedGenre)
+	}
+
+	return !isBlocked
+}
+
+type suppressedHistory struct {
+	history suggestionlog.History
+}
+
+func (f suppressedHistory) Name() string {
+	return HistoryFilterName
+}
+
+func (f suppressedHistory) Keep(track spotify.FullTrack, album spotify.FullAlbum) bool {
+	return f.history.Get(track).Count == 0
+}
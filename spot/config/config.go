@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -14,6 +15,7 @@ const (
 
 	OutputTypeConsole                = "console"
 	OutputTypePlaylist               = "playlist"
+	OutputTypeJSON                   = "json"
 	CredentialsFlowClientCredentials = "client-credentials"
 	CredentialsFlowRedirect          = "redirect"
 
@@ -21,23 +23,104 @@ const (
 	OperationTypeRecommendations    = "recommendation"
 	OperationTypeCheckTrackExists   = "check-track"
 	OperationTypeCheckPlaylistHoles = "check-playlist-holes"
+	OperationTypeSync               = "sync"
+	OperationTypeLearnWeights       = "learn-weights"
+	OperationTypeBlockAdd           = "block-add"
+	OperationTypeBlockRemove        = "block-remove"
+	OperationTypeAllowAdd           = "allow-add"
+	OperationTypeAllowRemove        = "allow-remove"
+	OperationTypeBlockCurrentArtist = "block-current-artist"
+	OperationTypeShowLists          = "show-lists"
+	OperationTypeNewReleases        = "new-releases"
+	OperationTypeRelatedArtists     = "related-artists"
+	OperationTypeListeningReport    = "listening-report"
+	OperationTypeArchive            = "archive"
+	OperationTypeRadio              = "radio"
 
 	CountrySweden = "SE"
 
+	ExplicitModeAny      = "any"
+	ExplicitModeExplicit = "explicit"
+	ExplicitModeClean    = "clean"
+
+	LibrarySourcePlaylists         = "playlists"
+	LibrarySourceLikedSongs        = "liked-songs"
+	LibrarySourceSavedAlbums       = "saved-albums"
+	LibrarySourceFollowedPlaylists = "followed-playlists"
+
+	LikedSongsName  = "Liked Songs"
+	SavedAlbumsName = "Saved Albums"
+
+	TimeRangeShort  = "short"
+	TimeRangeMedium = "medium"
+	TimeRangeLong   = "long"
+	TimeRangeRecent = "recent"
+
+	CrawlTrackSourceTop    = "top"
+	CrawlTrackSourceLatest = "latest"
+
+	HistoryModeDownrank = "downrank"
+	HistoryModeSuppress = "suppress"
+
+	MatchStrictnessExact  = "exact"
+	MatchStrictnessNormal = "normal"
+	MatchStrictnessLoose  = "loose"
+
+	LooseMatchMinSimilarity = 0.85
+
+	ArtistAffinityMax   = "max"
+	ArtistAffinitySum   = "sum"
+	ArtistAffinityMean  = "mean"
+	ArtistAffinityFirst = "first"
+
 	defaultPlaylistPattern = "^Metal ([0-9]+)"
 	CacheFilename          = ".ignored/.cache.json"
 	TokenCacheFilename     = ".ignored/.token-cache.json"
+	LibraryStoreFilename   = ".ignored/.library.json"
+
+	ArtistGenreCacheFilename  = ".ignored/.artist-genres.json"
+	AudioFeatureCacheFilename = ".ignored/.audio-features.json"
+	SuggestionLogFilename     = ".ignored/.suggestion-log.json"
+	FilterListFilename        = ".ignored/.filter-lists.json"
+	NewReleaseStateFilename   = ".ignored/.new-release-scan.json"
+	SeedRotationFilename      = ".ignored/.seed-rotation.json"
+
+	FollowedPlaylistCacheFilename = ".ignored/.followed-playlists.json"
+	LikedSongsCacheFilename       = ".ignored/.liked-songs.json"
+	SavedAlbumsCacheFilename      = ".ignored/.saved-albums.json"
+
+	FeedbackMinSamples = 30
+	FeedbackMinAgeDays = 7
+
+	defaultArtistAliasFilename     = ".ignored/.artist-aliases.json"
+	defaultScoringProfilesFilename = ".ignored/.scoring-profiles.json"
+
+	DefaultScoringProfileName = "default"
 
 	DiscoverWeeklyName = "Discover Weekly"
 	ReleaseRadarName   = "Release Radar"
 
+	SpotifyOwnerID = "spotify"
+
+	PlaylistKindDiscoverWeekly = "discover-weekly"
+	PlaylistKindReleaseRadar   = "release-radar"
+
 	MinimumAlbumTotalCount = 3
 	AlbumChunkSize         = 20
+	ArtistChunkSize        = 50
+	AudioFeatureChunkSize  = 100
+
+	AudioFeatureMinSpread = 0.05
+
+	RecommendationRequestBudget = 5
 
 	NumberPaddingSize = 20
 
 	ArtistJoinCharacter = ","
 
+	SpottedArchivePlaylistBase = "Spotted™ archive %s %s"
+	SpottedRadioPlaylistBase   = "Spotted™ radio %s %s"
+
 	spottedPlaylistBase = "Spotted™ %s %s"
 	redirectURLBase     = "http://%s:%d/authenticate"
 )
@@ -50,15 +133,64 @@ var (
 
 	PlaylistNamePattern = defaultPlaylistPattern
 
-	DiscoveryPlaylistNames   = []string{DiscoverWeeklyName, ReleaseRadarName}
-	DiscoveryPlaylistNameMap = map[string]string{
-		DiscoverWeeklyName: DiscoverWeeklyName,
-		ReleaseRadarName:   ReleaseRadarName,
-	}
+	LibrarySources = []string{LibrarySourcePlaylists}
 
-	FavouredPlaylistName       = ReleaseRadarName
+	DiscoverWeeklyNames = []string{DiscoverWeeklyName, "Upptäck veckan"}
+	ReleaseRadarNames   = []string{ReleaseRadarName, "Releaseradar"}
+	DiscoverWeeklyIDs   = []string{}
+	ReleaseRadarIDs     = []string{}
+
+	FavouredPlaylistKind       = PlaylistKindReleaseRadar
 	FavouredPlaylistAddedScore = 20
 
+	DefaultScoringProfile = map[string]float64{
+		"freshness":          0.3,
+		"artist-affinity":    1,
+		"word-penalty":       1,
+		"favoured-playlist":  1,
+		"suggestion-history": 10,
+		"provenance":         10,
+		"source-bonus":       1,
+	}
+
+	AllowlistBoost = 50.0
+
+	FreshnessMaxScore     = 100.0
+	FreshnessHalfLifeDays = 90.0
+
+	DiscoverySources = []string{}
+
+	NewReleaseArtistLimit = 50
+	NewReleaseDefaultDays = 28
+
+	TimeRanges    = []string{TimeRangeShort, TimeRangeMedium, TimeRangeLong}
+	SeedTimeRange = TimeRangeMedium
+
+	SeedPoolSize            = 20
+	SeedSetCount            = 5
+	SeedArtistsPerSet       = 1
+	SeedTracksPerSet        = 0
+	SeedArtists             = []string{}
+	SeedTracks              = []string{}
+	SeedGenres              = []string{}
+	SeedPlaylistName        = ""
+	TrackAttributeOverrides = []string{}
+
+	CrawlSeedArtistCount = 5
+	CrawlDepth           = 2
+	CrawlRequestBudget   = 100
+	CrawlTracksPerArtist = 3
+	CrawlTrackSource     = CrawlTrackSourceTop
+
+	ArchiveDate  = ""
+	CopyArchives = false
+
+	RadioSeedTrack          = ""
+	RadioSize               = 30
+	RadioMaxTracksPerArtist = 2
+
+	GenreBlocklist = []string{}
+
 	WordPenaltyMap = map[string]int{
 		"instrumental": -50,
 		"acoustic":     -30,
@@ -80,13 +212,53 @@ var (
 		OperationTypeRecommendations,
 		time.Now().Format("2006-01-02"),
 	)
+	SpottedNewReleasesPlaylistName = fmt.Sprintf(
+		spottedPlaylistBase,
+		OperationTypeNewReleases,
+		time.Now().Format("2006-01-02"),
+	)
+	SpottedRelatedArtistsPlaylistName = fmt.Sprintf(
+		spottedPlaylistBase,
+		OperationTypeRelatedArtists,
+		time.Now().Format("2006-01-02"),
+	)
 
 	CredentialsFlow = CredentialsFlowClientCredentials
 
 	OutputType    = OutputTypeConsole
+	Explain       = false
 	OperationType = OperationTypeDiscovery
 
 	Country = CountrySweden
+
+	MatchStrictness = MatchStrictnessNormal
+
+	ArtistAffinityCombination = ArtistAffinityMax
+	ArtistAliasFilename       = defaultArtistAliasFilename
+
+	ScoringProfile          = DefaultScoringProfileName
+	ScoringProfilesFilename = defaultScoringProfilesFilename
+	ApplyLearnedWeights     = false
+
+	ListEntryKind  = ""
+	ListEntryValue = ""
+	ListEntryNote  = ""
+
+	HistoryMode       = HistoryModeDownrank
+	HistoryWindowDays = 28
+	ShowHistory       = false
+
+	MinDurationSeconds = 0
+	MaxDurationSeconds = 0
+	ReleaseWindowDays  = 0
+	ExplicitMode       = ExplicitModeAny
+	MinAlbumSize       = MinimumAlbumTotalCount + 1
+	MinPopularity      = 0
+	MaxPopularity      = 100
+	RequireMarket      = false
+	AlbumTypes         = []string{}
+
+	RecommendationMinReleaseYear = 2016
 )
 
 var usernameFlag = flag.String(
@@ -98,7 +270,13 @@ var usernameFlag = flag.String(
 var outputTypeFlag = flag.String(
 	"output-type",
 	OutputTypeConsole,
-	"The method. \"console\" or \"playlist\"",
+	"The method. \"console\", \"playlist\" or \"json\"",
+)
+
+var explainFlag = flag.Bool(
+	"explain",
+	false,
+	"Print the score breakdown of every suggestion",
 )
 
 var addressFlag = flag.String(
@@ -137,16 +315,395 @@ var countryFlag = flag.String(
 	"The country to base recommendations on. Example: SE",
 )
 
-func init() {
+var matchStrictnessFlag = flag.String(
+	"match-strictness",
+	MatchStrictnessNormal,
+	"How loosely to match candidates against known tracks. \"exact\", \"normal\" or \"loose\"",
+)
+
+var artistAffinityFlag = flag.String(
+	"artist-affinity",
+	ArtistAffinityMax,
+	"How to combine the affinity of multi-artist credits. \"max\", \"sum\", \"mean\" or \"first\"",
+)
+
+var artistAliasesFlag = flag.String(
+	"artist-aliases",
+	defaultArtistAliasFilename,
+	"Path to a JSON file mapping canonical artist IDs to their alias IDs and names",
+)
+
+var scoringProfileFlag = flag.String(
+	"scoring-profile",
+	DefaultScoringProfileName,
+	"The name of the scoring profile to rank suggestions with",
+)
+
+var scoringProfilesFlag = flag.String(
+	"scoring-profiles",
+	defaultScoringProfilesFilename,
+	"Path to a JSON file mapping profile names to scoring component weights",
+)
+
+var genreBlocklistFlag = flag.String(
+	"genre-blocklist",
+	"",
+	"Comma separated genres to drop suggestions in. Example: \"pop,hip hop\"",
+)
+
+var applyFlag = flag.Bool(
+	"apply",
+	false,
+	"Write the learned weights to the current scoring profile",
+)
+
+var listKindFlag = flag.String(
+	"list-kind",
+	"artist",
+	"The kind of block/allow list entry. \"artist\", \"album\", \"track\", \"label\" or \"regex\"",
+)
+
+var listValueFlag = flag.String(
+	"list-value",
+	"",
+	"The block/allow list entry value. A Spotify ID, URI or URL, a label name or a regex",
+)
+
+var listNoteFlag = flag.String(
+	"list-note",
+	"",
+	"An optional note on why the block/allow list entry was added",
+)
+
+var historyModeFlag = flag.String(
+	"history-mode",
+	HistoryModeDownrank,
+	"How to treat tracks suggested within the history window. \"downrank\" or \"suppress\"",
+)
+
+var historyWindowDaysFlag = flag.Int(
+	"history-window-days",
+	28,
+	"The number of days previous suggestions are taken into account",
+)
+
+var showHistoryFlag = flag.Bool(
+	"show-history",
+	false,
+	"Show how many times each track has been suggested before",
+)
+
+var minDurationFlag = flag.Int(
+	"min-duration",
+	0,
+	"Drop candidates shorter than this many seconds. 0 disables the filter",
+)
+
+var maxDurationFlag = flag.Int(
+	"max-duration",
+	0,
+	"Drop candidates longer than this many seconds. 0 disables the filter",
+)
+
+var releaseWindowDaysFlag = flag.Int(
+	"release-window-days",
+	0,
+	"Drop candidates released more than this many days ago. 0 disables the filter",
+)
+
+var recommendationMinReleaseYearFlag = flag.Int(
+	"recommendation-min-release-year",
+	2016,
+	"Drop recommendations released before this year. 0 disables the filter",
+)
+
+var explicitFlag = flag.String(
+	"explicit",
+	ExplicitModeAny,
+	"Which candidates to keep by lyrics. \"any\", \"explicit\" or \"clean\"",
+)
+
+var minAlbumSizeFlag = flag.Int(
+	"min-album-size",
+	MinimumAlbumTotalCount+1,
+	"Drop candidates from albums with fewer tracks than this",
+)
+
+var minPopularityFlag = flag.Int(
+	"min-popularity",
+	0,
+	"Drop candidates less popular than this, between 0 and 100",
+)
+
+var maxPopularityFlag = flag.Int(
+	"max-popularity",
+	100,
+	"Drop candidates more popular than this, between 0 and 100",
+)
+
+var requireMarketFlag = flag.Bool(
+	"require-market",
+	false,
+	"Drop candidates not available in the configured country",
+)
+
+var albumTypesFlag = flag.String(
+	"album-types",
+	"",
+	"Comma separated album types to keep. Example: \"album,single\"",
+)
+
+var freshnessHalfLifeFlag = flag.Float64(
+	"freshness-half-life-days",
+	90,
+	"The number of days it takes for the freshness score of a release to halve",
+)
+
+var discoverySourcesFlag = flag.String(
+	"discovery-sources",
+	"",
+	"Comma separated playlist URIs, URLs or IDs to discover from, each optionally suffixed by =<score bonus>",
+)
+
+var discoverWeeklyNamesFlag = flag.String(
+	"discover-weekly-names",
+	strings.Join(DiscoverWeeklyNames, ","),
+	"Comma separated localized names of Discover Weekly",
+)
+
+var releaseRadarNamesFlag = flag.String(
+	"release-radar-names",
+	strings.Join(ReleaseRadarNames, ","),
+	"Comma separated localized names of Release Radar",
+)
+
+var discoverWeeklyIDsFlag = flag.String(
+	"discover-weekly-ids",
+	"",
+	"Comma separated IDs or links of Discover Weekly playlists, for when the name isn't recognised",
+)
+
+var releaseRadarIDsFlag = flag.String(
+	"release-radar-ids",
+	"",
+	"Comma separated IDs or links of Release Radar playlists, for when the name isn't recognised",
+)
+
+var newReleaseArtistsFlag = flag.Int(
+	"new-release-artists",
+	50,
+	"The number of library artists, by track count, to scan for new releases",
+)
+
+var newReleaseDaysFlag = flag.Int(
+	"new-release-days",
+	28,
+	"The number of days back to look for new releases by artists not scanned before",
+)
+
+var crawlSeedArtistsFlag = flag.Int(
+	"crawl-seed-artists",
+	5,
+	"The number of library artists, by track count, to crawl related artists from",
+)
+
+var crawlDepthFlag = flag.Int(
+	"crawl-depth",
+	2,
+	"How many steps away from the library artists to crawl related artists",
+)
+
+var crawlRequestBudgetFlag = flag.Int(
+	"crawl-request-budget",
+	100,
+	"The maximum number of requests to spend crawling related artists and fetching their tracks",
+)
+
+var crawlTracksPerArtistFlag = flag.Int(
+	"crawl-tracks-per-artist",
+	3,
+	"The number of tracks to suggest by each reached artist",
+)
+
+var crawlTrackSourceFlag = flag.String(
+	"crawl-track-source",
+	CrawlTrackSourceTop,
+	"Which tracks of reached artists to suggest. \"top\" or \"latest\"",
+)
+
+var librarySourcesFlag = flag.String(
+	"library-sources",
+	LibrarySourcePlaylists,
+	"Comma separated sources of known tracks. \"playlists\", \"liked-songs\", \"saved-albums\" or \"followed-playlists\"",
+)
+
+var seedTimeRangeFlag = flag.String(
+	"seed-time-range",
+	TimeRangeMedium,
+	"The listening time range to seed recommendations from. \"short\", \"medium\", \"long\" or \"recent\"",
+)
+
+var seedPoolSizeFlag = flag.Int(
+	"seed-pool-size",
+	20,
+	"The number of top artists and tracks to rotate recommendation seeds through",
+)
+
+var seedSetsFlag = flag.Int(
+	"seed-sets",
+	5,
+	"The number of differently seeded recommendation requests to make",
+)
+
+var seedArtistsPerSetFlag = flag.Int(
+	"seed-artists-per-set",
+	1,
+	"The number of artists from the seed pool in each recommendation request",
+)
+
+var seedTracksPerSetFlag = flag.Int(
+	"seed-tracks-per-set",
+	0,
+	"The number of tracks from the seed pool in each recommendation request",
+)
+
+var seedArtistsFlag = flag.String(
+	"seed-artists",
+	"",
+	"Comma separated artist URIs, URLs or IDs to seed every recommendation request with",
+)
+
+var seedTracksFlag = flag.String(
+	"seed-tracks",
+	"",
+	"Comma separated track URIs, URLs or IDs to seed every recommendation request with",
+)
+
+var seedGenresFlag = flag.String(
+	"seed-genres",
+	"",
+	"Comma separated genres to seed every recommendation request with. Example: \"metal,black-metal\"",
+)
+
+var seedPlaylistFlag = flag.String(
+	"seed-playlist",
+	"",
+	"The name of a library playlist to draw seeds from instead of the listening history",
+)
+
+var trackAttributesFlag = flag.String(
+	"track-attributes",
+	"",
+	"Comma separated track attributes overriding the derived ones. Example: \"target_energy=0.8,min_tempo=120\"",
+)
+
+var archiveDateFlag = flag.String(
+	"archive-date",
+	"",
+	"Generate discovery suggestions from the playlists archived the week up to this date. Example: 2018-10-01",
+)
+
+var archivePlaylistsFlag = flag.Bool(
+	"archive-playlists",
+	false,
+	"Copy archived discovery playlists into dated playlists",
+)
+
+var radioTrackFlag = flag.String(
+	"radio-track",
+	"",
+	"The track URI, URL or ID to seed the radio with. Defaults to the currently playing track",
+)
+
+var radioSizeFlag = flag.Int(
+	"radio-size",
+	30,
+	"The number of tracks on the radio",
+)
+
+var radioMaxPerArtistFlag = flag.Int(
+	"radio-max-per-artist",
+	2,
+	"The maximum number of radio tracks by the same artist. 0 disables the limit",
+)
+
+// Parse parses the command line flags into the config, leaving the defaults
+// for the flags not given. It's to be called once, before using the config.
+func Parse() {
 	flag.Parse()
 
 	UserName = *usernameFlag
 	OutputType = *outputTypeFlag
+	Explain = *explainFlag
 	Port = *portFlag
 	Address = *addressFlag
 	CredentialsFlow = *credentialsFlowFlag
 	RedirectURL = fmt.Sprintf(redirectURLBase, *addressFlag, *portFlag)
 	PlaylistNamePattern = *playlistNamePatternFlag
+	LibrarySources = splitList(*librarySourcesFlag)
 	OperationType = *operationFlag
 	Country = *countryFlag
+	MatchStrictness = *matchStrictnessFlag
+	ArtistAffinityCombination = *artistAffinityFlag
+	ArtistAliasFilename = *artistAliasesFlag
+	ScoringProfile = *scoringProfileFlag
+	ScoringProfilesFilename = *scoringProfilesFlag
+	GenreBlocklist = splitList(*genreBlocklistFlag)
+	ApplyLearnedWeights = *applyFlag
+	ListEntryKind = *listKindFlag
+	ListEntryValue = *listValueFlag
+	ListEntryNote = *listNoteFlag
+	HistoryMode = *historyModeFlag
+	HistoryWindowDays = *historyWindowDaysFlag
+	ShowHistory = *showHistoryFlag
+	MinDurationSeconds = *minDurationFlag
+	MaxDurationSeconds = *maxDurationFlag
+	ReleaseWindowDays = *releaseWindowDaysFlag
+	RecommendationMinReleaseYear = *recommendationMinReleaseYearFlag
+	ExplicitMode = *explicitFlag
+	MinAlbumSize = *minAlbumSizeFlag
+	MinPopularity = *minPopularityFlag
+	MaxPopularity = *maxPopularityFlag
+	RequireMarket = *requireMarketFlag
+	AlbumTypes = splitList(*albumTypesFlag)
+	FreshnessHalfLifeDays = *freshnessHalfLifeFlag
+	DiscoverySources = splitList(*discoverySourcesFlag)
+	DiscoverWeeklyNames = splitList(*discoverWeeklyNamesFlag)
+	ReleaseRadarNames = splitList(*releaseRadarNamesFlag)
+	DiscoverWeeklyIDs = splitList(*discoverWeeklyIDsFlag)
+	ReleaseRadarIDs = splitList(*releaseRadarIDsFlag)
+	NewReleaseArtistLimit = *newReleaseArtistsFlag
+	NewReleaseDefaultDays = *newReleaseDaysFlag
+	ArchiveDate = *archiveDateFlag
+	CopyArchives = *archivePlaylistsFlag
+	RadioSeedTrack = *radioTrackFlag
+	RadioSize = *radioSizeFlag
+	RadioMaxTracksPerArtist = *radioMaxPerArtistFlag
+	SeedTimeRange = *seedTimeRangeFlag
+	SeedPoolSize = *seedPoolSizeFlag
+	SeedSetCount = *seedSetsFlag
+	SeedArtistsPerSet = *seedArtistsPerSetFlag
+	SeedTracksPerSet = *seedTracksPerSetFlag
+	SeedArtists = splitList(*seedArtistsFlag)
+	SeedTracks = splitList(*seedTracksFlag)
+	SeedGenres = splitList(*seedGenresFlag)
+	SeedPlaylistName = *seedPlaylistFlag
+	TrackAttributeOverrides = splitList(*trackAttributesFlag)
+	CrawlSeedArtistCount = *crawlSeedArtistsFlag
+	CrawlDepth = *crawlDepthFlag
+	CrawlRequestBudget = *crawlRequestBudgetFlag
+	CrawlTracksPerArtist = *crawlTracksPerArtistFlag
+	CrawlTrackSource = *crawlTrackSourceFlag
+}
+
+func splitList(value string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+
+	return items
 }
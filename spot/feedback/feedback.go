@@ -0,0 +1,241 @@
+package feedback
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/scoring"
+	"github.com/kristofferostlund/spot/spot/suggestionlog"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+const (
+	iterations     = 1000
+	learningRate   = 0.1
+	regularization = 0.01
+	minimumSpread  = 1e-9
+)
+
+type Weight struct {
+	Name    string
+	Current float64
+	Learned float64
+	IsFit   bool
+}
+
+type Fit struct {
+	Weights  []Weight
+	Samples  int
+	Accepted int
+}
+
+func FitWeights(entries []suggestionlog.Entry, profile scoring.Profile, decidedBefore time.Time) (Fit, error) {
+	fit := Fit{Weights: []Weight{}}
+	names := []string{}
+
+	for name := range profile {
+		if !scoring.IsFixed(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	samples := [][]float64{}
+	labels := []float64{}
+
+	for _, entry := range entries {
+		// Recent suggestions that haven't been accepted might still be added
+		// to a playlist, so only learn from them once they've had time to be.
+		if !entry.IsAccepted() && !entry.SuggestedAt.Before(decidedBefore) {
+			continue
+		}
+
+		raw := map[string]float64{}
+		for _, contribution := range entry.Breakdown {
+			raw[contribution.Name] = contribution.Raw
+		}
+
+		sample := []float64{}
+		for _, name := range names {
+			sample = append(sample, raw[name])
+		}
+
+		samples = append(samples, sample)
+
+		if entry.IsAccepted() {
+			fit.Accepted++
+			labels = append(labels, 1)
+		} else {
+			labels = append(labels, 0)
+		}
+	}
+
+	fit.Samples = len(samples)
+
+	if fit.Samples < config.FeedbackMinSamples {
+		return fit, fmt.Errorf(
+			"Need at least %d decided suggestions to learn from, found %d",
+			config.FeedbackMinSamples,
+			fit.Samples,
+		)
+	}
+
+	if fit.Accepted == 0 || fit.Accepted == fit.Samples {
+		return fit, fmt.Errorf("Need both accepted and ignored suggestions to learn from, %d/%d were accepted", fit.Accepted, fit.Samples)
+	}
+
+	means, spreads := getStatistics(samples, len(names))
+	coefficients := fitLogisticRegression(standardize(samples, means, spreads), labels)
+
+	currentTotal := 0.0
+	learnedTotal := 0.0
+
+	for index, name := range names {
+		weight := Weight{Name: name, Current: profile[name], Learned: profile[name]}
+
+		if spreads[index] > minimumSpread {
+			weight.Learned = coefficients[index] / spreads[index]
+			weight.IsFit = true
+
+			currentTotal += math.Abs(weight.Current)
+			learnedTotal += math.Abs(weight.Learned)
+		}
+
+		fit.Weights = append(fit.Weights, weight)
+	}
+
+	// Only the relation between the weights matters for the ranking, so keep the
+	// scale of the current profile to make the two comparable.
+	if learnedTotal > 0 {
+		for index, weight := range fit.Weights {
+			if weight.IsFit {
+				fit.Weights[index].Learned = weight.Learned * currentTotal / learnedTotal
+			}
+		}
+	}
+
+	return fit, nil
+}
+
+func (f Fit) GetProfile() scoring.Profile {
+	profile := scoring.Profile{}
+
+	for _, weight := range f.Weights {
+		profile[weight.Name] = weight.Learned
+	}
+
+	return profile
+}
+
+func CreatePrintableTable(fit Fit) string {
+	output := fmt.Sprintf(
+		"Learned from %d suggestion(s), %d of which were accepted\n\n",
+		fit.Samples,
+		fit.Accepted,
+	)
+
+	output += fmt.Sprintf(
+		"   %s %10s %10s\n",
+		utils.FixedWidthString("Component", 30),
+		"Current",
+		"Learned",
+	)
+
+	for _, weight := range fit.Weights {
+		note := ""
+		if !weight.IsFit {
+			note = " (no variation, kept)"
+		}
+
+		output += fmt.Sprintf(
+			"   %s %10.3f %10.3f%s\n",
+			utils.FixedWidthString(weight.Name, 30),
+			weight.Current,
+			weight.Learned,
+			note,
+		)
+	}
+
+	return output
+}
+
+func getStatistics(samples [][]float64, featureCount int) ([]float64, []float64) {
+	means := make([]float64, featureCount)
+	spreads := make([]float64, featureCount)
+
+	for feature := 0; feature < featureCount; feature++ {
+		values := []float64{}
+		for _, sample := range samples {
+			values = append(values, sample[feature])
+		}
+
+		means[feature] = utils.AverageFloat(values)
+
+		squaredDiffs := []float64{}
+		for _, value := range values {
+			squaredDiffs = append(squaredDiffs, math.Pow(value-means[feature], 2))
+		}
+
+		spreads[feature] = math.Sqrt(utils.AverageFloat(squaredDiffs))
+	}
+
+	return means, spreads
+}
+
+func standardize(samples [][]float64, means, spreads []float64) [][]float64 {
+	standardized := [][]float64{}
+
+	for _, sample := range samples {
+		values := make([]float64, len(sample))
+
+		for feature, value := range sample {
+			if spreads[feature] > minimumSpread {
+				values[feature] = (value - means[feature]) / spreads[feature]
+			}
+		}
+
+		standardized = append(standardized, values)
+	}
+
+	return standardized
+}
+
+func fitLogisticRegression(samples [][]float64, labels []float64) []float64 {
+	featureCount := len(samples[0])
+	coefficients := make([]float64, featureCount)
+	bias := 0.0
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		gradients := make([]float64, featureCount)
+		biasGradient := 0.0
+
+		for index, sample := range samples {
+			prediction := bias
+			for feature, value := range sample {
+				prediction += coefficients[feature] * value
+			}
+
+			diff := 1/(1+math.Exp(-prediction)) - labels[index]
+
+			for feature, value := range sample {
+				gradients[feature] += diff * value
+			}
+
+			biasGradient += diff
+		}
+
+		count := float64(len(samples))
+
+		for feature := range coefficients {
+			coefficients[feature] -= learningRate * (gradients[feature]/count + regularization*coefficients[feature])
+		}
+
+		bias -= learningRate * biasGradient / count
+	}
+
+	return coefficients
+}
@@ -0,0 +1,226 @@
+package feedback
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/scoring"
+	"github.com/kristofferostlund/spot/spot/suggestionlog"
+)
+
+var (
+	decidedBefore = time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	decidedAt     = decidedBefore.AddDate(0, 0, -14)
+	undecidedAt   = decidedBefore.AddDate(0, 0, 3)
+)
+
+func createEntry(suggestedAt time.Time, isAccepted bool, raw map[string]float64) suggestionlog.Entry {
+	entry := suggestionlog.Entry{SuggestedAt: suggestedAt}
+
+	for name, value := range raw {
+		entry.Breakdown = append(entry.Breakdown, scoring.Contribution{Name: name, Raw: value})
+	}
+
+	if isAccepted {
+		entry.AcceptedAt = suggestedAt.AddDate(0, 0, 1)
+	}
+
+	return entry
+}
+
+// createSeparableEntries creates entries where only "signal" tells accepted
+// suggestions from ignored ones, "noise" varies regardless of the outcome and
+// "constant" never varies.
+func createSeparableEntries(count int) []suggestionlog.Entry {
+	entries := []suggestionlog.Entry{}
+
+	for index := 0; index < count; index++ {
+		isAccepted := index%2 == 0
+
+		signal := 10.0 + float64(index%5)
+		if isAccepted {
+			signal += 20
+		}
+
+		entries = append(entries, createEntry(decidedAt, isAccepted, map[string]float64{
+			"signal":   signal,
+			"noise":    float64((index / 2) % 7),
+			"constant": 1,
+		}))
+	}
+
+	return entries
+}
+
+func createMixedEntries(count int, suggestedAt time.Time, isAccepted func(index int) bool) []suggestionlog.Entry {
+	entries := []suggestionlog.Entry{}
+
+	for index := 0; index < count; index++ {
+		entries = append(entries, createEntry(suggestedAt, isAccepted(index), map[string]float64{
+			"signal": float64(index),
+		}))
+	}
+
+	return entries
+}
+
+func everyOther(index int) bool {
+	return index%2 == 0
+}
+
+func TestFitWeightsGate(t *testing.T) {
+	profile := scoring.Profile{"signal": 1}
+	minSamples := config.FeedbackMinSamples
+
+	tests := []struct {
+		name            string
+		entries         []suggestionlog.Entry
+		expectedSamples int
+		expectErr       bool
+	}{
+		{
+			name:            "no entries",
+			entries:         []suggestionlog.Entry{},
+			expectedSamples: 0,
+			expectErr:       true,
+		},
+		{
+			name:            "one sample short",
+			entries:         createMixedEntries(minSamples-1, decidedAt, everyOther),
+			expectedSamples: minSamples - 1,
+			expectErr:       true,
+		},
+		{
+			name:            "exactly enough samples",
+			entries:         createMixedEntries(minSamples, decidedAt, everyOther),
+			expectedSamples: minSamples,
+			expectErr:       false,
+		},
+		{
+			name: "recent unaccepted suggestions aren't counted",
+			entries: append(
+				createMixedEntries(minSamples-1, decidedAt, everyOther),
+				createMixedEntries(10, undecidedAt, func(int) bool { return false })...,
+			),
+			expectedSamples: minSamples - 1,
+			expectErr:       true,
+		},
+		{
+			name: "recent accepted suggestions are counted",
+			entries: append(
+				createMixedEntries(minSamples-1, decidedAt, everyOther),
+				createMixedEntries(1, undecidedAt, func(int) bool { return true })...,
+			),
+			expectedSamples: minSamples,
+			expectErr:       false,
+		},
+		{
+			name:            "only accepted suggestions",
+			entries:         createMixedEntries(minSamples, decidedAt, func(int) bool { return true }),
+			expectedSamples: minSamples,
+			expectErr:       true,
+		},
+		{
+			name:            "only ignored suggestions",
+			entries:         createMixedEntries(minSamples, decidedAt, func(int) bool { return false }),
+			expectedSamples: minSamples,
+			expectErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit, err := FitWeights(tt.entries, profile, decidedBefore)
+
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected an error, got none")
+			}
+
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if fit.Samples != tt.expectedSamples {
+				t.Errorf("Expected %d samples, got %d", tt.expectedSamples, fit.Samples)
+			}
+		})
+	}
+}
+
+func TestFitWeightsSeparable(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile scoring.Profile
+	}{
+		{
+			name:    "equal weights",
+			profile: scoring.Profile{"signal": 1, "noise": 1, "constant": 1},
+		},
+		{
+			name:    "signal underweighted",
+			profile: scoring.Profile{"signal": 0.1, "noise": 10, "constant": 5},
+		},
+		{
+			name:    "signal negative",
+			profile: scoring.Profile{"signal": -1, "noise": 1, "constant": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit, err := FitWeights(createSeparableEntries(60), tt.profile, decidedBefore)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			weights := map[string]Weight{}
+			for _, weight := range fit.Weights {
+				weights[weight.Name] = weight
+			}
+
+			signal, noise, constant := weights["signal"], weights["noise"], weights["constant"]
+
+			if !signal.IsFit || signal.Learned <= 0 {
+				t.Errorf("Expected a positive learned signal weight, got %+v", signal)
+			}
+
+			if math.Abs(signal.Learned) <= 10*math.Abs(noise.Learned) {
+				t.Errorf("Expected signal to dominate noise, got %f and %f", signal.Learned, noise.Learned)
+			}
+
+			if constant.IsFit || constant.Learned != tt.profile["constant"] {
+				t.Errorf("Expected the constant weight to be kept at %f, got %+v", tt.profile["constant"], constant)
+			}
+
+			currentTotal := math.Abs(tt.profile["signal"]) + math.Abs(tt.profile["noise"])
+			learnedTotal := math.Abs(signal.Learned) + math.Abs(noise.Learned)
+
+			if math.Abs(currentTotal-learnedTotal) > 1e-9 {
+				t.Errorf("Expected the learned weights to keep the scale %f, got %f", currentTotal, learnedTotal)
+			}
+		})
+	}
+}
+
+func TestFitGetProfile(t *testing.T) {
+	fit := Fit{Weights: []Weight{
+		{Name: "signal", Current: 1, Learned: 3, IsFit: true},
+		{Name: "constant", Current: 2, Learned: 2, IsFit: false},
+	}}
+
+	profile := fit.GetProfile()
+
+	expected := scoring.Profile{"signal": 3, "constant": 2}
+
+	if len(profile) != len(expected) {
+		t.Fatalf("Expected %d weights, got %d", len(expected), len(profile))
+	}
+
+	for name, weight := range expected {
+		if profile[name] != weight {
+			t.Errorf("Expected %s to be %f, got %f", name, weight, profile[name])
+		}
+	}
+}
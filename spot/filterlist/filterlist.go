@@ -0,0 +1,208 @@
+package filterlist
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+const (
+	ListBlock = "block"
+	ListAllow = "allow"
+
+	KindArtist = "artist"
+	KindAlbum  = "album"
+	KindTrack  = "track"
+	KindLabel  = "label"
+	KindRegex  = "regex"
+)
+
+var Kinds = []string{KindArtist, KindAlbum, KindTrack, KindLabel, KindRegex}
+
+type Entry struct {
+	Kind    string
+	Value   string
+	Note    string
+	AddedAt time.Time
+}
+
+type Lists struct {
+	Block []Entry
+	Allow []Entry
+}
+
+func Read() (Lists, error) {
+	lists := Lists{Block: []Entry{}, Allow: []Entry{}}
+
+	if err := cache.ReadCache(config.FilterListFilename, &lists); err != nil {
+		return lists, err
+	}
+
+	return lists, nil
+}
+
+func Write(lists Lists) error {
+	return cache.WriteCache(config.FilterListFilename, lists)
+}
+
+func CreateEntry(kind, value, note string) (Entry, error) {
+	entry := Entry{Kind: kind, Value: strings.TrimSpace(value), Note: note, AddedAt: time.Now()}
+
+	switch kind {
+	case KindArtist, KindAlbum, KindTrack:
+		entry.Value = string(utils.ParseSpotifyID(entry.Value))
+	case KindLabel:
+		entry.Value = strings.ToLower(entry.Value)
+	case KindRegex:
+		if _, err := regexp.Compile(entry.Value); err != nil {
+			return entry, fmt.Errorf("Failed to compile regex %s: %v", entry.Value, err)
+		}
+	default:
+		return entry, fmt.Errorf("Invalid list entry kind %s, expected one of %v", kind, Kinds)
+	}
+
+	if entry.Value == "" {
+		return entry, fmt.Errorf("Missing a value for the %s entry", kind)
+	}
+
+	return entry, nil
+}
+
+func (l *Lists) Add(list string, entry Entry) error {
+	entries, err := l.get(list)
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range entries {
+		if existing.Kind == entry.Kind && existing.Value == entry.Value {
+			return nil
+		}
+	}
+
+	l.set(list, append(entries, entry))
+
+	return nil
+}
+
+func (l *Lists) Remove(list string, entry Entry) (bool, error) {
+	entries, err := l.get(list)
+	if err != nil {
+		return false, err
+	}
+
+	remaining := []Entry{}
+
+	for _, existing := range entries {
+		if existing.Kind != entry.Kind || existing.Value != entry.Value {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	l.set(list, remaining)
+
+	return len(remaining) != len(entries), nil
+}
+
+func (l Lists) IsBlocked(track spotify.FullTrack, album spotify.FullAlbum) (Entry, bool) {
+	return findMatch(l.Block, track, album)
+}
+
+func (l Lists) IsAllowed(track spotify.FullTrack, album spotify.FullAlbum) (Entry, bool) {
+	return findMatch(l.Allow, track, album)
+}
+
+func CreatePrintableTable(lists Lists) string {
+	output := ""
+	titles := map[string]string{ListBlock: "Blocked", ListAllow: "Allowed"}
+
+	for _, list := range []string{ListBlock, ListAllow} {
+		entries, _ := lists.get(list)
+
+		output += fmt.Sprintf("%s (%d)\n", titles[list], len(entries))
+
+		for _, entry := range entries {
+			output += fmt.Sprintf(
+				"   %s %s %s\n",
+				utils.FixedWidthString(entry.Kind, 8),
+				utils.FixedWidthString(entry.Value, 40),
+				entry.Note,
+			)
+		}
+
+		output += "\n"
+	}
+
+	return output
+}
+
+func (l *Lists) get(list string) ([]Entry, error) {
+	switch list {
+	case ListBlock:
+		return l.Block, nil
+	case ListAllow:
+		return l.Allow, nil
+	default:
+		return []Entry{}, fmt.Errorf("Invalid list %s, expected %s or %s", list, ListBlock, ListAllow)
+	}
+}
+
+func (l *Lists) set(list string, entries []Entry) {
+	switch list {
+	case ListBlock:
+		l.Block = entries
+	case ListAllow:
+		l.Allow = entries
+	}
+}
+
+func findMatch(entries []Entry, track spotify.FullTrack, album spotify.FullAlbum) (Entry, bool) {
+	for _, entry := range entries {
+		if matches(entry, track, album) {
+			return entry, true
+		}
+	}
+
+	return Entry{}, false
+}
+
+func matches(entry Entry, track spotify.FullTrack, album spotify.FullAlbum) bool {
+	switch entry.Kind {
+	case KindArtist:
+		for _, artist := range append(append([]spotify.SimpleArtist{}, track.Artists...), album.Artists...) {
+			if string(artist.ID) == entry.Value {
+				return true
+			}
+		}
+	case KindAlbum:
+		return string(track.Album.ID) == entry.Value || string(album.ID) == entry.Value
+	case KindTrack:
+		return string(track.ID) == entry.Value
+	case KindLabel:
+		// The label isn't exposed on albums by the API version in use, but it's
+		// part of the copyright lines, e.g. "(P) 2018 Nuclear Blast GmbH".
+		for _, copyright := range album.Copyrights {
+			if strings.Contains(strings.ToLower(copyright.Text), entry.Value) {
+				return true
+			}
+		}
+	case KindRegex:
+		re, err := regexp.Compile(entry.Value)
+		if err != nil {
+			return false
+		}
+
+		return re.MatchString(track.Name) ||
+			re.MatchString(album.Name) ||
+			re.MatchString(utils.JoinArtists(track.Artists, ", "))
+	}
+
+	return false
+}
@@ -3,6 +3,7 @@ package fullalbum
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -48,6 +49,10 @@ func GetMany(client spotify.Client, albumIDs []spotify.ID) ([]spotify.FullAlbum,
 	chunks := utils.ChunkIDs(uncachedAlbumIDs, config.AlbumChunkSize)
 
 	for _, chunk := range chunks {
+		if len(chunk) == 0 {
+			continue
+		}
+
 		albumChunk, err := client.GetAlbums(chunk...)
 		if err != nil {
 			return albums, fmt.Errorf("Failed to get %d album(s): %v", len(albumIDs), err)
@@ -105,24 +110,113 @@ func GetAlbumByTrack(client spotify.Client, track spotify.FullTrack) (spotify.Fu
 	return album, nil
 }
 
+func ListArtistReleasesSince(client spotify.Client, artistID spotify.ID, since time.Time) ([]spotify.FullAlbum, error) {
+	simpleAlbums, err := listSimpleArtistAlbums(client, artistID)
+	if err != nil {
+		return []spotify.FullAlbum{}, err
+	}
+
+	albumIDs := []spotify.ID{}
+
+	for _, album := range simpleAlbums {
+		if getReleasePeriodEnd(album).After(since) {
+			albumIDs = append(albumIDs, album.ID)
+		}
+	}
+
+	return GetMany(client, albumIDs)
+}
+
+// GetLatestRelease finds the artist's latest album or single. Every request it
+// needs to make is first taken from take, giving up once it returns false.
+func GetLatestRelease(
+	client spotify.Client,
+	artistID spotify.ID,
+	take func() bool,
+) (spotify.FullAlbum, bool, error) {
+	simpleAlbums, isComplete, err := listSimpleArtistAlbumsWithin(client, artistID, take)
+	if err != nil || !isComplete || len(simpleAlbums) == 0 {
+		return spotify.FullAlbum{}, false, err
+	}
+
+	latest := simpleAlbums[0]
+	for _, album := range simpleAlbums[1:] {
+		if getReleasePeriodEnd(album).After(getReleasePeriodEnd(latest)) {
+			latest = album
+		}
+	}
+
+	if _, isCached := albumCache[latest.ID]; !isCached && !take() {
+		return spotify.FullAlbum{}, false, nil
+	}
+
+	album, err := Get(client, latest.ID)
+	if err != nil {
+		return album, false, err
+	}
+
+	return album, true, nil
+}
+
 func listArtistAlbums(client spotify.Client, artistID spotify.ID) ([]spotify.FullAlbum, error) {
+	albums, err := listSimpleArtistAlbums(client, artistID)
+	if err != nil {
+		return []spotify.FullAlbum{}, err
+	}
+
+	return GetMany(client, utils.GetSpotifyIDs(albums))
+}
+
+func listSimpleArtistAlbums(client spotify.Client, artistID spotify.ID) ([]spotify.SimpleAlbum, error) {
+	albums, _, err := listSimpleArtistAlbumsWithin(client, artistID, func() bool { return true })
+
+	return albums, err
+}
+
+// listSimpleArtistAlbumsWithin pages the artist's albums as long as take allows
+// another request, and tells whether all of them were listed.
+func listSimpleArtistAlbumsWithin(
+	client spotify.Client,
+	artistID spotify.ID,
+	take func() bool,
+) ([]spotify.SimpleAlbum, bool, error) {
 	pageLimit := 50
 	totalCount := -1
 	albumType := spotify.AlbumTypeAlbum | spotify.AlbumTypeSingle
 	albums := []spotify.SimpleAlbum{}
 
 	for totalCount != len(albums) {
+		if !take() {
+			return albums, false, nil
+		}
+
 		offset := len(albums)
 		options := spotify.Options{Limit: &pageLimit, Offset: &offset}
 
 		page, err := client.GetArtistAlbumsOpt(artistID, &options, &albumType)
 		if err != nil {
-			return []spotify.FullAlbum{}, fmt.Errorf("Failed to get albums for the artist %s: %v", artistID, err)
+			return albums, false, fmt.Errorf("Failed to get albums for the artist %s: %v", artistID, err)
 		}
 
 		albums = append(albums, page.Albums...)
 		totalCount = page.Total
 	}
 
-	return GetMany(client, utils.GetSpotifyIDs(albums))
+	return albums, true, nil
+}
+
+// getReleasePeriodEnd returns when the period covered by the release date
+// ends, so a release dated "2018" isn't considered older than one from March.
+func getReleasePeriodEnd(album spotify.SimpleAlbum) time.Time {
+	full := spotify.FullAlbum{ReleaseDate: album.ReleaseDate, ReleaseDatePrecision: album.ReleaseDatePrecision}
+	releaseDate := full.ReleaseDateTime()
+
+	switch album.ReleaseDatePrecision {
+	case "year":
+		return releaseDate.AddDate(1, 0, 0)
+	case "month":
+		return releaseDate.AddDate(0, 1, 0)
+	}
+
+	return releaseDate.AddDate(0, 0, 1)
 }
@@ -0,0 +1,178 @@
+package fuzzymatch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/artistidentity"
+	"github.com/kristofferostlund/spot/spot/config"
+)
+
+const versionKeywords = `remaster|remastered|live|version|edit|mono|stereo|demo|re-recorded|rerecorded|bonus|deluxe|anniversary|single|radio`
+
+var (
+	versionSuffixPattern = regexp.MustCompile(`\s+-\s+.*\b(` + versionKeywords + `)\b.*$`)
+	bracketPattern       = regexp.MustCompile(`[\(\[][^\)\]]*\b(feat|ft|featuring|with|` + versionKeywords + `)\b[^\)\]]*[\)\]]`)
+	featuringPattern     = regexp.MustCompile(`\s+(feat|ft|featuring)\b.*$`)
+
+	diacriticReplacer = strings.NewReplacer(
+		"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+		"ç", "c",
+		"è", "e", "é", "e", "ê", "e", "ë", "e",
+		"ì", "i", "í", "i", "î", "i", "ï", "i",
+		"ñ", "n",
+		"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+		"ù", "u", "ú", "u", "û", "u", "ü", "u",
+		"ý", "y", "ÿ", "y",
+		"ß", "ss",
+	)
+)
+
+type Matcher struct {
+	strictness     string
+	tracksByTitle  map[string][]spotify.FullTrack
+	tracksByArtist map[string][]spotify.FullTrack
+}
+
+func CreateMatcher(tracks []spotify.FullTrack, strictness string) Matcher {
+	matcher := Matcher{
+		strictness:     strictness,
+		tracksByTitle:  map[string][]spotify.FullTrack{},
+		tracksByArtist: map[string][]spotify.FullTrack{},
+	}
+
+	if strictness == config.MatchStrictnessExact {
+		return matcher
+	}
+
+	for _, track := range tracks {
+		title := NormalizeTitle(track.Name)
+		matcher.tracksByTitle[title] = append(matcher.tracksByTitle[title], track)
+
+		for _, artist := range track.Artists {
+			key := artistidentity.GetKey(artist)
+			matcher.tracksByArtist[key] = append(matcher.tracksByArtist[key], track)
+		}
+	}
+
+	return matcher
+}
+
+func (m Matcher) FindMatch(track spotify.FullTrack) (spotify.FullTrack, bool) {
+	if m.strictness == config.MatchStrictnessExact {
+		return spotify.FullTrack{}, false
+	}
+
+	title := NormalizeTitle(track.Name)
+
+	for _, candidate := range m.tracksByTitle[title] {
+		if shareArtist(candidate.Artists, track.Artists) {
+			return candidate, true
+		}
+	}
+
+	if m.strictness != config.MatchStrictnessLoose {
+		return spotify.FullTrack{}, false
+	}
+
+	for _, artist := range track.Artists {
+		for _, candidate := range m.tracksByArtist[artistidentity.GetKey(artist)] {
+			if Similarity(title, NormalizeTitle(candidate.Name)) >= config.LooseMatchMinSimilarity {
+				return candidate, true
+			}
+		}
+	}
+
+	return spotify.FullTrack{}, false
+}
+
+func NormalizeTitle(title string) string {
+	normalized := diacriticReplacer.Replace(strings.ToLower(title))
+
+	normalized = versionSuffixPattern.ReplaceAllString(normalized, "")
+	normalized = bracketPattern.ReplaceAllString(normalized, "")
+	normalized = featuringPattern.ReplaceAllString(normalized, "")
+
+	normalized = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return ' '
+	}, normalized)
+
+	return strings.Join(strings.Fields(normalized), " ")
+}
+
+func Similarity(a, b string) float64 {
+	longest := len([]rune(a))
+	if length := len([]rune(b)); length > longest {
+		longest = length
+	}
+
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(EditDistance(a, b))/float64(longest)
+}
+
+func EditDistance(a, b string) int {
+	source := []rune(a)
+	target := []rune(b)
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
+
+func shareArtist(a, b []spotify.SimpleArtist) bool {
+	keys := map[string]bool{}
+
+	for _, artist := range a {
+		keys[artistidentity.GetKey(artist)] = true
+	}
+
+	for _, artist := range b {
+		if keys[artistidentity.GetKey(artist)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+
+	return min
+}
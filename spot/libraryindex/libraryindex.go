@@ -0,0 +1,169 @@
+package libraryindex
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/artistidentity"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/fuzzymatch"
+	"github.com/kristofferostlund/spot/spot/playlist"
+	"github.com/kristofferostlund/spot/spot/spotifytrack"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type Occurrence struct {
+	PlaylistID   spotify.ID
+	PlaylistName string
+	Position     int
+	AddedAt      time.Time
+}
+
+type ArtistCount struct {
+	Artist     spotify.SimpleArtist
+	TrackCount int
+}
+
+type Index struct {
+	Playlists    []playlist.Playlist
+	Sources      []playlist.Playlist
+	Tracks       []spotify.FullTrack
+	ArtistGroups spotifytrack.ArtistFullTrackMap
+
+	trackMap         spotifytrack.FullTrackMap
+	matcher          fuzzymatch.Matcher
+	occurrencesByKey map[string][]Occurrence
+	occurrencesByID  map[spotify.ID][]Occurrence
+	tracksByAlbum    map[spotify.ID][]spotify.FullTrack
+}
+
+// Create indexes the playlists matching the pattern along with the other
+// library sources, e.g. Liked Songs, which are only used for lookups.
+func Create(playlists []playlist.Playlist, sources []playlist.Playlist) Index {
+	allPlaylists := append(append([]playlist.Playlist{}, playlists...), sources...)
+
+	index := Index{
+		Playlists:        playlists,
+		Sources:          sources,
+		occurrencesByKey: map[string][]Occurrence{},
+		occurrencesByID:  map[spotify.ID][]Occurrence{},
+		tracksByAlbum:    map[spotify.ID][]spotify.FullTrack{},
+	}
+
+	for _, list := range allPlaylists {
+		for position, track := range list.Tracks {
+			occurrence := Occurrence{
+				PlaylistID:   list.ID,
+				PlaylistName: list.Name,
+				Position:     position,
+			}
+
+			if position < len(list.TrackAddedAt) {
+				occurrence.AddedAt = list.TrackAddedAt[position]
+			}
+
+			key := fulltrack.GetKey(track)
+
+			index.occurrencesByKey[key] = append(index.occurrencesByKey[key], occurrence)
+			index.occurrencesByID[track.ID] = append(index.occurrencesByID[track.ID], occurrence)
+		}
+	}
+
+	index.Tracks = fulltrack.GetUnique(playlist.FlattenTracks(allPlaylists))
+	index.trackMap = fulltrack.CreateMap(index.Tracks)
+	index.matcher = fuzzymatch.CreateMatcher(index.Tracks, config.MatchStrictness)
+	index.ArtistGroups = fulltrack.GroupByArtists(index.Tracks)
+
+	for _, track := range index.Tracks {
+		index.tracksByAlbum[track.Album.ID] = append(index.tracksByAlbum[track.Album.ID], track)
+	}
+
+	return index
+}
+
+func (i Index) Contains(track spotify.FullTrack) bool {
+	if _, exists := i.occurrencesByID[track.ID]; exists {
+		return true
+	}
+
+	if fulltrack.InMap(i.trackMap, track) {
+		return true
+	}
+
+	if match, exists := i.matcher.FindMatch(track); exists {
+		logrus.Debugf(
+			"Considering %s by %s a version of the known track %s",
+			track.Name,
+			utils.JoinArtists(track.Artists, ", "),
+			match.Name,
+		)
+
+		return true
+	}
+
+	return false
+}
+
+func (i Index) Find(track spotify.FullTrack) []Occurrence {
+	return i.FindByReference(track.ID, fulltrack.GetKey(track))
+}
+
+func (i Index) FindByReference(id spotify.ID, key string) []Occurrence {
+	occurrences := []Occurrence{}
+	isListed := map[string]bool{}
+
+	candidates := append(
+		append([]Occurrence{}, i.occurrencesByID[id]...),
+		i.occurrencesByKey[key]...,
+	)
+
+	for _, occurrence := range candidates {
+		occurrenceKey := fmt.Sprintf("%s:%d", occurrence.PlaylistID, occurrence.Position)
+		if isListed[occurrenceKey] {
+			continue
+		}
+
+		isListed[occurrenceKey] = true
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences
+}
+
+func (i Index) GetTracksByArtist(artist spotify.SimpleArtist) []spotify.FullTrack {
+	return i.ArtistGroups[artistidentity.GetKey(artist)]
+}
+
+func (i Index) GetTracksByAlbum(albumID spotify.ID) []spotify.FullTrack {
+	return i.tracksByAlbum[albumID]
+}
+
+// GetArtistsByTrackCount lists every library artist once, the ones with the
+// most tracks first.
+func (i Index) GetArtistsByTrackCount() []ArtistCount {
+	artists := []ArtistCount{}
+	isListed := map[string]bool{}
+
+	for _, track := range i.Tracks {
+		for _, artist := range track.Artists {
+			key := artistidentity.GetKey(artist)
+			if isListed[key] {
+				continue
+			}
+
+			isListed[key] = true
+			artists = append(artists, ArtistCount{Artist: artist, TrackCount: len(i.ArtistGroups[key])})
+		}
+	}
+
+	sort.SliceStable(artists, func(a, b int) bool {
+		return artists[a].TrackCount > artists[b].TrackCount
+	})
+
+	return artists
+}
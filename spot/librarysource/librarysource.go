@@ -0,0 +1,256 @@
+package librarysource
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/playlist"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type savedAlbum struct {
+	ID      spotify.ID
+	AddedAt time.Time
+	Tracks  []spotify.FullTrack
+}
+
+// GetSources returns the configured library sources other than the playlists
+// matching the pattern, with Liked Songs and saved albums as playlists of their own.
+// The followed playlists are picked from the already listed simple playlists.
+func GetSources(
+	client spotify.Client,
+	user *spotify.User,
+	simplePlaylists []spotify.SimplePlaylist,
+) ([]playlist.Playlist, error) {
+	sources := []playlist.Playlist{}
+
+	for _, source := range config.LibrarySources {
+		switch source {
+		case config.LibrarySourcePlaylists:
+			continue
+		case config.LibrarySourceLikedSongs:
+			likedSongs, err := GetLikedSongs(client)
+			if err != nil {
+				return sources, err
+			}
+
+			sources = append(sources, likedSongs)
+		case config.LibrarySourceSavedAlbums:
+			savedAlbums, err := GetSavedAlbums(client)
+			if err != nil {
+				return sources, err
+			}
+
+			sources = append(sources, savedAlbums)
+		case config.LibrarySourceFollowedPlaylists:
+			followed, err := playlist.GetFollowedPlaylists(client, user, simplePlaylists, config.PlaylistNamePattern)
+			if err != nil {
+				return sources, err
+			}
+
+			sources = append(sources, followed...)
+		default:
+			return sources, fmt.Errorf("Unknown library source %s", source)
+		}
+	}
+
+	return sources, nil
+}
+
+func IsEnabled(source string) bool {
+	for _, enabled := range config.LibrarySources {
+		if enabled == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetLikedSongs lists the saved tracks, newest first, until reaching tracks
+// that are cached, as saved tracks are listed in the order they were added.
+func GetLikedSongs(client spotify.Client) (playlist.Playlist, error) {
+	likedSongs := createPlaylist(config.LibrarySourceLikedSongs, config.LikedSongsName)
+	cached := createPlaylist(config.LibrarySourceLikedSongs, config.LikedSongsName)
+
+	if err := cache.ReadCache(config.LikedSongsCacheFilename, &cached); err != nil {
+		return likedSongs, err
+	}
+
+	pageLimit := 50
+	totalCount := -1
+
+	for totalCount != len(likedSongs.Tracks) {
+		offset := len(likedSongs.Tracks)
+		options := &spotify.Options{Limit: &pageLimit, Offset: &offset}
+
+		page, err := client.CurrentUsersTracksOpt(options)
+		if err != nil {
+			return likedSongs, fmt.Errorf("Failed to list liked songs: %v", err)
+		}
+
+		totalCount = page.Total
+
+		if len(page.Tracks) == 0 {
+			break
+		}
+
+		for _, savedTrack := range page.Tracks {
+			addedAt, _ := time.Parse(spotify.TimestampLayout, savedTrack.AddedAt)
+
+			if index := findTrack(cached, savedTrack.ID, addedAt); index != -1 {
+				remaining := len(cached.Tracks) - index
+
+				if len(likedSongs.Tracks)+remaining == totalCount {
+					likedSongs.Tracks = append(likedSongs.Tracks, cached.Tracks[index:]...)
+					likedSongs.TrackAddedAt = append(likedSongs.TrackAddedAt, cached.TrackAddedAt[index:]...)
+
+					break
+				}
+			}
+
+			likedSongs.Tracks = append(likedSongs.Tracks, savedTrack.FullTrack)
+			likedSongs.TrackAddedAt = append(likedSongs.TrackAddedAt, addedAt)
+		}
+	}
+
+	logrus.Infof("Listed %4v tracks for %s", len(likedSongs.Tracks), likedSongs.Name)
+
+	if err := cache.WriteCache(config.LikedSongsCacheFilename, likedSongs); err != nil {
+		return likedSongs, err
+	}
+
+	return likedSongs, nil
+}
+
+// GetSavedAlbums lists the saved albums and combines their tracks, only
+// fetching the full tracks of albums which aren't cached.
+func GetSavedAlbums(client spotify.Client) (playlist.Playlist, error) {
+	savedAlbums := createPlaylist(config.LibrarySourceSavedAlbums, config.SavedAlbumsName)
+	cachedAlbums := []savedAlbum{}
+	albums := []savedAlbum{}
+
+	if err := cache.ReadCache(config.SavedAlbumsCacheFilename, &cachedAlbums); err != nil {
+		return savedAlbums, err
+	}
+
+	cachedByID := map[spotify.ID]savedAlbum{}
+	for _, album := range cachedAlbums {
+		cachedByID[album.ID] = album
+	}
+
+	pageLimit := 50
+	totalCount := -1
+	fetchedCount := 0
+
+	for totalCount != len(albums) {
+		offset := len(albums)
+		options := &spotify.Options{Limit: &pageLimit, Offset: &offset}
+
+		page, err := client.CurrentUsersAlbumsOpt(options)
+		if err != nil {
+			return savedAlbums, fmt.Errorf("Failed to list saved albums: %v", err)
+		}
+
+		totalCount = page.Total
+
+		if len(page.Albums) == 0 {
+			break
+		}
+
+		for _, saved := range page.Albums {
+			addedAt, _ := time.Parse(spotify.TimestampLayout, saved.AddedAt)
+
+			album, isCached := cachedByID[saved.ID]
+			if !isCached {
+				simpleTracks, err := listAlbumTracks(client, saved.ID, saved.Tracks)
+				if err != nil {
+					return savedAlbums, err
+				}
+
+				tracks, err := fulltrack.GetMany(client, utils.GetSpotifyIDs(simpleTracks))
+				if err != nil {
+					return savedAlbums, err
+				}
+
+				fetchedCount++
+				album = savedAlbum{ID: saved.ID, Tracks: tracks}
+			}
+
+			album.AddedAt = addedAt
+			albums = append(albums, album)
+		}
+	}
+
+	for _, album := range albums {
+		for _, track := range album.Tracks {
+			savedAlbums.Tracks = append(savedAlbums.Tracks, track)
+			savedAlbums.TrackAddedAt = append(savedAlbums.TrackAddedAt, album.AddedAt)
+		}
+	}
+
+	logrus.Infof(
+		"Listed %4v tracks on %d saved album(s), fetched %d album(s)",
+		len(savedAlbums.Tracks),
+		len(albums),
+		fetchedCount,
+	)
+
+	if err := cache.WriteCache(config.SavedAlbumsCacheFilename, albums); err != nil {
+		return savedAlbums, err
+	}
+
+	return savedAlbums, nil
+}
+
+// listAlbumTracks pages the rest of the album's tracks, as only the first page
+// is included with the saved album.
+func listAlbumTracks(
+	client spotify.Client,
+	albumID spotify.ID,
+	firstPage spotify.SimpleTrackPage,
+) ([]spotify.SimpleTrack, error) {
+	pageLimit := 50
+	tracks := append([]spotify.SimpleTrack{}, firstPage.Tracks...)
+
+	for len(tracks) < firstPage.Total {
+		page, err := client.GetAlbumTracksOpt(albumID, pageLimit, len(tracks))
+		if err != nil {
+			return tracks, fmt.Errorf("Failed to list tracks of album %s: %v", albumID, err)
+		}
+
+		if len(page.Tracks) == 0 {
+			break
+		}
+
+		tracks = append(tracks, page.Tracks...)
+	}
+
+	return tracks, nil
+}
+
+func createPlaylist(id, name string) playlist.Playlist {
+	return playlist.Playlist{
+		ID:              spotify.ID(id),
+		Name:            name,
+		Tracks:          []spotify.FullTrack{},
+		TrackAddedAt:    []time.Time{},
+		TracksPopulated: true,
+	}
+}
+
+func findTrack(list playlist.Playlist, id spotify.ID, addedAt time.Time) int {
+	for index, track := range list.Tracks {
+		if track.ID == id && index < len(list.TrackAddedAt) && list.TrackAddedAt[index].Equal(addedAt) {
+			return index
+		}
+	}
+
+	return -1
+}
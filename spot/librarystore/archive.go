@@ -0,0 +1,156 @@
+package librarystore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/playlist"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+// Archive is a snapshot of a discovery playlist, with the tracks in the order
+// they had in the playlist.
+type Archive struct {
+	PlaylistID   spotify.ID
+	PlaylistName string
+	Kind         string
+	SnapshotID   string
+	Tracks       []Track
+	ArchivedAt   time.Time
+}
+
+func CreateArchive(list playlist.Playlist, archivedAt time.Time) Archive {
+	stored := CreatePlaylist(list, archivedAt)
+
+	return Archive{
+		PlaylistID:   list.ID,
+		PlaylistName: list.Name,
+		Kind:         list.Kind,
+		SnapshotID:   list.SnapshotID,
+		Tracks:       stored.Tracks,
+		ArchivedAt:   archivedAt,
+	}
+}
+
+// Archive stores the playlist unless its current snapshot already is archived.
+func (s *Store) Archive(list playlist.Playlist, archivedAt time.Time) (Archive, bool) {
+	for _, archive := range s.Archives {
+		if archive.PlaylistID == list.ID && archive.SnapshotID == list.SnapshotID {
+			return archive, false
+		}
+	}
+
+	archive := CreateArchive(list, archivedAt)
+	s.Archives = append(s.Archives, archive)
+
+	return archive, true
+}
+
+// GetArchivesAt returns the latest archive of every playlist from the week
+// leading up to and including the date.
+func (s Store) GetArchivesAt(date time.Time) []Archive {
+	archives := []Archive{}
+	latest := map[spotify.ID]int{}
+	end := date.AddDate(0, 0, 1)
+	start := date.AddDate(0, 0, -6)
+
+	for _, archive := range s.Archives {
+		if archive.ArchivedAt.Before(start) || !archive.ArchivedAt.Before(end) {
+			continue
+		}
+
+		if index, exists := latest[archive.PlaylistID]; exists {
+			if archive.ArchivedAt.After(archives[index].ArchivedAt) {
+				archives[index] = archive
+			}
+
+			continue
+		}
+
+		latest[archive.PlaylistID] = len(archives)
+		archives = append(archives, archive)
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].PlaylistName < archives[j].PlaylistName
+	})
+
+	return archives
+}
+
+// Restore recreates the archived playlist, fetching its tracks by ID.
+func (a Archive) Restore(client spotify.Client) (playlist.Playlist, error) {
+	restored := playlist.Playlist{
+		ID:              a.PlaylistID,
+		Name:            a.PlaylistName,
+		Kind:            a.Kind,
+		SnapshotID:      a.SnapshotID,
+		Tracks:          []spotify.FullTrack{},
+		TrackAddedAt:    []time.Time{},
+		TracksPopulated: true,
+	}
+
+	ids := []spotify.ID{}
+	isListed := map[spotify.ID]bool{}
+
+	for _, track := range a.Tracks {
+		if track.ID != "" && !isListed[track.ID] {
+			isListed[track.ID] = true
+			ids = append(ids, track.ID)
+		}
+	}
+
+	fetchedTracks, err := fulltrack.GetMany(client, ids)
+	if err != nil {
+		return restored, err
+	}
+
+	knownTracks := map[spotify.ID]spotify.FullTrack{}
+	for _, track := range fetchedTracks {
+		knownTracks[track.ID] = track
+	}
+
+	// Tracks no longer available are skipped, while repeated tracks keep their own dates
+	for _, archived := range a.Tracks {
+		track, exists := knownTracks[archived.ID]
+		if !exists {
+			continue
+		}
+
+		restored.Tracks = append(restored.Tracks, track)
+		restored.TrackAddedAt = append(restored.TrackAddedAt, archived.AddedAt)
+	}
+
+	return restored, nil
+}
+
+func CreatePrintableArchiveReport(archives []Archive, isNew []bool) string {
+	output := fmt.Sprintf(
+		"%s %s %s %s\n",
+		utils.FixedWidthString("Playlist", 30),
+		utils.FixedWidthString("Tracks", 6),
+		utils.FixedWidthString("Archived", 16),
+		utils.FixedWidthString("Status", 10),
+	)
+
+	for index, archive := range archives {
+		status := "unchanged"
+		if index < len(isNew) && isNew[index] {
+			status = "archived"
+		}
+
+		output += fmt.Sprintf(
+			"%s %-6d %s %s\n",
+			utils.FixedWidthString(archive.PlaylistName, 30),
+			len(archive.Tracks),
+			archive.ArchivedAt.Format("2006-01-02 15:04"),
+			status,
+		)
+	}
+
+	return output
+}
@@ -0,0 +1,254 @@
+package librarystore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/playlist"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type Track struct {
+	ID      spotify.ID
+	Key     string
+	Name    string
+	Artists string
+	AddedAt time.Time
+}
+
+type Playlist struct {
+	ID         spotify.ID
+	Name       string
+	SnapshotID string
+	Tracks     []Track
+	SyncedAt   time.Time
+}
+
+type Change struct {
+	PlaylistID   spotify.ID
+	PlaylistName string
+	Added        []Track
+	Removed      []Track
+	IsNew        bool
+	IsDeleted    bool
+	SyncedAt     time.Time
+}
+
+type Store struct {
+	Playlists    map[spotify.ID]Playlist
+	Changes      []Change
+	Archives     []Archive
+	LastSyncedAt time.Time
+}
+
+func CreateStore() Store {
+	return Store{
+		Playlists: map[spotify.ID]Playlist{},
+		Changes:   []Change{},
+		Archives:  []Archive{},
+	}
+}
+
+func Read() (Store, error) {
+	store := CreateStore()
+
+	if err := cache.ReadCache(config.LibraryStoreFilename, &store); err != nil {
+		return store, err
+	}
+
+	if store.Playlists == nil {
+		store.Playlists = map[spotify.ID]Playlist{}
+	}
+
+	return store, nil
+}
+
+func Write(store Store) error {
+	return cache.WriteCache(config.LibraryStoreFilename, store)
+}
+
+func CreateTrack(track spotify.FullTrack, addedAt time.Time) Track {
+	return Track{
+		ID:      track.ID,
+		Key:     fulltrack.GetKey(track),
+		Name:    track.Name,
+		Artists: utils.JoinArtists(track.Artists, ", "),
+		AddedAt: addedAt,
+	}
+}
+
+func CreatePlaylist(list playlist.Playlist, syncedAt time.Time) Playlist {
+	stored := Playlist{
+		ID:         list.ID,
+		Name:       list.Name,
+		SnapshotID: list.SnapshotID,
+		Tracks:     []Track{},
+		SyncedAt:   syncedAt,
+	}
+
+	for position, track := range list.Tracks {
+		addedAt := time.Time{}
+		if position < len(list.TrackAddedAt) {
+			addedAt = list.TrackAddedAt[position]
+		}
+
+		stored.Tracks = append(stored.Tracks, CreateTrack(track, addedAt))
+	}
+
+	return stored
+}
+
+func (s *Store) Sync(playlists []playlist.Playlist, syncedAt time.Time) []Change {
+	changes := []Change{}
+	isSynced := map[spotify.ID]bool{}
+
+	for _, list := range playlists {
+		isSynced[list.ID] = true
+		current := CreatePlaylist(list, syncedAt)
+		previous, exists := s.Playlists[list.ID]
+
+		s.Playlists[list.ID] = current
+
+		if !exists {
+			changes = append(changes, Change{
+				PlaylistID:   current.ID,
+				PlaylistName: current.Name,
+				Added:        current.Tracks,
+				Removed:      []Track{},
+				IsNew:        true,
+				SyncedAt:     syncedAt,
+			})
+
+			continue
+		}
+
+		if previous.SnapshotID == current.SnapshotID {
+			continue
+		}
+
+		added, removed := diffTracks(previous.Tracks, current.Tracks)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+
+		changes = append(changes, Change{
+			PlaylistID:   current.ID,
+			PlaylistName: current.Name,
+			Added:        added,
+			Removed:      removed,
+			SyncedAt:     syncedAt,
+		})
+	}
+
+	for id, previous := range s.Playlists {
+		if isSynced[id] {
+			continue
+		}
+
+		delete(s.Playlists, id)
+
+		changes = append(changes, Change{
+			PlaylistID:   previous.ID,
+			PlaylistName: previous.Name,
+			Added:        []Track{},
+			Removed:      previous.Tracks,
+			IsDeleted:    true,
+			SyncedAt:     syncedAt,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		current := utils.MakeStringSortable(changes[i].PlaylistName, config.NumberPaddingSize)
+		next := utils.MakeStringSortable(changes[j].PlaylistName, config.NumberPaddingSize)
+
+		return current > next
+	})
+
+	s.Changes = append(s.Changes, changes...)
+	s.LastSyncedAt = syncedAt
+
+	return changes
+}
+
+func CreatePrintableReport(changes []Change, lastSyncedAt time.Time) string {
+	if lastSyncedAt.IsZero() {
+		return fmt.Sprintf("First sync, stored %d playlist(s)\n", len(changes))
+	}
+
+	output := fmt.Sprintf("Changes since %s\n", lastSyncedAt.Format("2006-01-02 15:04"))
+
+	if len(changes) == 0 {
+		return output + "   Nothing has changed\n"
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.IsNew:
+			output += fmt.Sprintf("\n%s (new playlist, %d tracks)\n", change.PlaylistName, len(change.Added))
+
+			continue
+		case change.IsDeleted:
+			output += fmt.Sprintf("\n%s (no longer matching, %d tracks)\n", change.PlaylistName, len(change.Removed))
+
+			continue
+		}
+
+		output += fmt.Sprintf("\n%s (+%d, -%d)\n", change.PlaylistName, len(change.Added), len(change.Removed))
+
+		for _, track := range change.Added {
+			output += fmt.Sprintf(
+				" + %s %s %s\n",
+				utils.FixedWidthString(track.Name, 30),
+				utils.FixedWidthString(track.Artists, 30),
+				track.AddedAt.Format("2006-01-02"),
+			)
+		}
+
+		for _, track := range change.Removed {
+			output += fmt.Sprintf(
+				" - %s %s\n",
+				utils.FixedWidthString(track.Name, 30),
+				utils.FixedWidthString(track.Artists, 30),
+			)
+		}
+	}
+
+	return output
+}
+
+func diffTracks(previous, current []Track) ([]Track, []Track) {
+	added := []Track{}
+	removed := []Track{}
+	previousCounts := map[spotify.ID]int{}
+	currentCounts := map[spotify.ID]int{}
+
+	for _, track := range previous {
+		previousCounts[track.ID]++
+	}
+
+	for _, track := range current {
+		currentCounts[track.ID]++
+
+		if currentCounts[track.ID] > previousCounts[track.ID] {
+			added = append(added, track)
+		}
+	}
+
+	seenCounts := map[spotify.ID]int{}
+
+	for _, track := range previous {
+		seenCounts[track.ID]++
+
+		if seenCounts[track.ID] > currentCounts[track.ID] {
+			removed = append(removed, track)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,266 @@
+package listening
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/artistidentity"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+const recentlyPlayedLimit = 50
+
+// Weights of each time range in the artist affinity, favouring current listening
+var timeRangeWeights = map[string]float64{
+	config.TimeRangeShort:  3,
+	config.TimeRangeMedium: 2,
+	config.TimeRangeLong:   1,
+	config.TimeRangeRecent: 3,
+}
+
+type Profile struct {
+	TopTracks      map[string][]spotify.FullTrack
+	TopArtists     map[string][]spotify.SimpleArtist
+	RecentlyPlayed []spotify.RecentlyPlayedItem
+	ArtistAffinity map[string]float64
+}
+
+func GetProfile(client spotify.Client) (Profile, error) {
+	profile := Profile{
+		TopTracks:      map[string][]spotify.FullTrack{},
+		TopArtists:     map[string][]spotify.SimpleArtist{},
+		RecentlyPlayed: []spotify.RecentlyPlayedItem{},
+		ArtistAffinity: map[string]float64{},
+	}
+
+	recentlyPlayed, err := GetRecentlyPlayed(client)
+	if err != nil {
+		return profile, err
+	}
+
+	profile.RecentlyPlayed = recentlyPlayed
+
+	for _, timeRange := range config.TimeRanges {
+		topTracks, err := GetTopTracks(client, timeRange, 50)
+		if err != nil {
+			return profile, err
+		}
+
+		topArtists, err := GetTopArtists(client, timeRange, 50)
+		if err != nil {
+			return profile, err
+		}
+
+		profile.TopTracks[timeRange] = topTracks
+		profile.TopArtists[timeRange] = topArtists
+	}
+
+	profile.TopArtists[config.TimeRangeRecent] = getRecentArtists(recentlyPlayed)
+
+	totalWeight := 0.0
+	for timeRange := range profile.TopArtists {
+		totalWeight += timeRangeWeights[timeRange]
+	}
+
+	// Normalised by the total weight, an artist topping every time range has an
+	// affinity of 1
+	for timeRange, artists := range profile.TopArtists {
+		for rank, artist := range artists {
+			key := artistidentity.GetKey(artist)
+			affinity := timeRangeWeights[timeRange] * float64(len(artists)-rank) / float64(len(artists))
+
+			profile.ArtistAffinity[key] += affinity / totalWeight
+		}
+	}
+
+	return profile, nil
+}
+
+// GetTopArtists lists the top artists of a time range, where the "recent" range
+// ranks the artists of the recently played tracks by how often they were played.
+func GetTopArtists(client spotify.Client, timeRange string, limit int) ([]spotify.SimpleArtist, error) {
+	artists := []spotify.SimpleArtist{}
+
+	if timeRange == config.TimeRangeRecent {
+		recentlyPlayed, err := GetRecentlyPlayed(client)
+		if err != nil {
+			return artists, err
+		}
+
+		artists = getRecentArtists(recentlyPlayed)
+		if len(artists) > limit {
+			artists = artists[:limit]
+		}
+
+		return artists, nil
+	}
+
+	page, err := client.CurrentUsersTopArtistsOpt(&spotify.Options{Limit: &limit, Timerange: &timeRange})
+	if err != nil {
+		return artists, fmt.Errorf("Failed to get user's %s term top artists: %v", timeRange, err)
+	}
+
+	for _, artist := range page.Artists {
+		artists = append(artists, artist.SimpleArtist)
+	}
+
+	return artists, nil
+}
+
+func GetTopTracks(client spotify.Client, timeRange string, limit int) ([]spotify.FullTrack, error) {
+	if timeRange == config.TimeRangeRecent {
+		recentlyPlayed, err := GetRecentlyPlayed(client)
+		if err != nil {
+			return []spotify.FullTrack{}, err
+		}
+
+		ids := []spotify.ID{}
+		for _, item := range recentlyPlayed {
+			if len(ids) < limit {
+				ids = append(ids, item.Track.ID)
+			}
+		}
+
+		return fulltrack.GetMany(client, ids)
+	}
+
+	page, err := client.CurrentUsersTopTracksOpt(&spotify.Options{Limit: &limit, Timerange: &timeRange})
+	if err != nil {
+		return []spotify.FullTrack{}, fmt.Errorf("Failed to get user's %s term top tracks: %v", timeRange, err)
+	}
+
+	return page.Tracks, nil
+}
+
+func GetRecentlyPlayed(client spotify.Client) ([]spotify.RecentlyPlayedItem, error) {
+	items, err := client.PlayerRecentlyPlayedOpt(&spotify.RecentlyPlayedOptions{Limit: recentlyPlayedLimit})
+	if err != nil {
+		return items, fmt.Errorf("Failed to get recently played tracks: %v", err)
+	}
+
+	return items, nil
+}
+
+// GetAffinity combines the listening affinity of the credited artists the same
+// way as the library affinity, capped at 1.
+func (p Profile) GetAffinity(artists []spotify.SimpleArtist) float64 {
+	values := []float64{}
+
+	for _, key := range artistidentity.GetKeys(artists) {
+		values = append(values, p.ArtistAffinity[key])
+	}
+
+	return math.Min(artistidentity.CombineAffinityValues(values), 1)
+}
+
+func CreatePrintableReport(profile Profile, library libraryindex.Index) string {
+	output := ""
+
+	for _, timeRange := range append(append([]string{}, config.TimeRanges...), config.TimeRangeRecent) {
+		artists := profile.TopArtists[timeRange]
+		newCount := 0
+		rows := ""
+
+		for rank, artist := range artists {
+			trackCount := len(library.GetTracksByArtist(artist))
+			note := ""
+
+			if trackCount == 0 {
+				newCount++
+				note = "not in library"
+			}
+
+			rows += fmt.Sprintf(
+				"%-3d %s %-14d %s\n",
+				rank+1,
+				utils.FixedWidthString(artist.Name, 30),
+				trackCount,
+				note,
+			)
+		}
+
+		output += fmt.Sprintf(
+			"\nTop artists, %s: %d/%d not in the library\n    %s %s\n%s",
+			getTimeRangeName(timeRange),
+			newCount,
+			len(artists),
+			utils.FixedWidthString("Artist", 30),
+			utils.FixedWidthString("Library tracks", 14),
+			rows,
+		)
+	}
+
+	output += "\n"
+
+	for _, timeRange := range config.TimeRanges {
+		tracks := profile.TopTracks[timeRange]
+
+		output += fmt.Sprintf(
+			"Top tracks, %s: %d/%d in the library\n",
+			getTimeRangeName(timeRange),
+			countKnown(library, tracks),
+			len(tracks),
+		)
+	}
+
+	recentTracks := []spotify.FullTrack{}
+	for _, item := range profile.RecentlyPlayed {
+		recentTracks = append(recentTracks, spotify.FullTrack{SimpleTrack: item.Track})
+	}
+
+	output += fmt.Sprintf(
+		"Recently played tracks: %d/%d in the library\n",
+		countKnown(library, recentTracks),
+		len(recentTracks),
+	)
+
+	return output
+}
+
+func getRecentArtists(items []spotify.RecentlyPlayedItem) []spotify.SimpleArtist {
+	artists := []spotify.SimpleArtist{}
+	playCounts := map[string]int{}
+
+	for _, item := range items {
+		for _, artist := range item.Track.Artists {
+			key := artistidentity.GetKey(artist)
+			if playCounts[key] == 0 {
+				artists = append(artists, artist)
+			}
+
+			playCounts[key]++
+		}
+	}
+
+	sort.SliceStable(artists, func(i, j int) bool {
+		return playCounts[artistidentity.GetKey(artists[i])] > playCounts[artistidentity.GetKey(artists[j])]
+	})
+
+	return artists
+}
+
+func countKnown(library libraryindex.Index, tracks []spotify.FullTrack) int {
+	count := 0
+
+	for _, track := range tracks {
+		if library.Contains(track) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func getTimeRangeName(timeRange string) string {
+	if timeRange == config.TimeRangeRecent {
+		return "recently played"
+	}
+
+	return timeRange + " term"
+}
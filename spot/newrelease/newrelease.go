@@ -0,0 +1,101 @@
+package newrelease
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/fullalbum"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type ScanState struct {
+	ArtistScannedAt map[spotify.ID]time.Time
+	LastScannedAt   time.Time
+}
+
+func ReadState() (ScanState, error) {
+	state := ScanState{ArtistScannedAt: map[spotify.ID]time.Time{}}
+
+	if err := cache.ReadCache(config.NewReleaseStateFilename, &state); err != nil {
+		return state, err
+	}
+
+	if state.ArtistScannedAt == nil {
+		state.ArtistScannedAt = map[spotify.ID]time.Time{}
+	}
+
+	return state, nil
+}
+
+func WriteState(state ScanState) error {
+	return cache.WriteCache(config.NewReleaseStateFilename, state)
+}
+
+// Scan lists the releases of the artists with the most library tracks since
+// each of them was last scanned, returning the tracks of those releases.
+func Scan(
+	client spotify.Client,
+	artists []libraryindex.ArtistCount,
+	state *ScanState,
+	scannedAt time.Time,
+) ([]spotify.FullTrack, error) {
+	tracks := []spotify.FullTrack{}
+	trackIDs := []spotify.ID{}
+	isListed := map[spotify.ID]bool{}
+	defaultSince := scannedAt.AddDate(0, 0, -config.NewReleaseDefaultDays)
+
+	if len(artists) > config.NewReleaseArtistLimit {
+		artists = artists[:config.NewReleaseArtistLimit]
+	}
+
+	for _, artistCount := range artists {
+		artist := artistCount.Artist
+		if artist.ID == "" {
+			continue
+		}
+
+		since, isScanned := state.ArtistScannedAt[artist.ID]
+		if !isScanned {
+			since = defaultSince
+		}
+
+		albums, err := fullalbum.ListArtistReleasesSince(client, artist.ID, since)
+		if err != nil {
+			return tracks, err
+		}
+
+		logrus.Infof(
+			"Found %d release(s) by %s (%d library tracks) since %s",
+			len(albums),
+			artist.Name,
+			artistCount.TrackCount,
+			since.Format("2006-01-02"),
+		)
+
+		for _, album := range albums {
+			if isListed[album.ID] {
+				continue
+			}
+
+			isListed[album.ID] = true
+			trackIDs = append(trackIDs, utils.GetSpotifyIDs(album.Tracks.Tracks)...)
+		}
+
+		state.ArtistScannedAt[artist.ID] = scannedAt
+	}
+
+	tracks, err := fulltrack.GetMany(client, trackIDs)
+	if err != nil {
+		return tracks, err
+	}
+
+	state.LastScannedAt = scannedAt
+
+	return tracks, nil
+}
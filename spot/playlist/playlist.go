@@ -5,104 +5,196 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
-
-	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"strings"
+	"time"
 
 	"github.com/kristofferostlund/spot/spot/cache"
 	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
 	"github.com/kristofferostlund/spot/spot/utils"
 	"github.com/sirupsen/logrus"
 
 	"github.com/zmb3/spotify"
 )
 
+const trackReferenceFields = "total,items(added_at,track(id))"
+
 type Playlist struct {
 	SimplePlaylist  spotify.SimplePlaylist
 	Tracks          []spotify.FullTrack
+	TrackAddedAt    []time.Time
 	ID              spotify.ID
 	Name            string
 	SnapshotID      string
 	TracksPopulated bool
+	ScoreBonus      float64
+	Kind            string
+}
+
+type Source struct {
+	ID    spotify.ID
+	Bonus float64
 }
 
 func CreatePlaylist(simplePlaylist spotify.SimplePlaylist) Playlist {
 	return Playlist{
 		SimplePlaylist:  simplePlaylist,
 		Tracks:          []spotify.FullTrack{},
+		TrackAddedAt:    []time.Time{},
 		ID:              simplePlaylist.ID,
 		Name:            simplePlaylist.Name,
 		SnapshotID:      simplePlaylist.SnapshotID,
 		TracksPopulated: false,
+		Kind:            GetKind(simplePlaylist),
 	}
 }
 
-func GetPlaylistsMatchingPattern(client spotify.Client, user *spotify.User, pattern string) ([]Playlist, error) {
-	playlists := []Playlist{}
-	cachedPlaylists := []Playlist{}
-
-	if err := cache.ReadCache(config.CacheFilename, &cachedPlaylists); err != nil {
-		return playlists, err
+// GetKind tells which of Spotify's generated playlists, if any, a playlist is.
+// It's owned by Spotify and identified by the configured IDs or its name, as
+// the names are localized, e.g. "Upptäck veckan" rather than "Discover Weekly".
+func GetKind(simplePlaylist spotify.SimplePlaylist) string {
+	if simplePlaylist.Owner.ID != config.SpotifyOwnerID {
+		return ""
 	}
 
-	simplePlaylists, err := listSimplePlaylists(client, user)
-	if err != nil {
-		return playlists, err
+	switch {
+	case isListedID(config.DiscoverWeeklyIDs, simplePlaylist.ID):
+		return config.PlaylistKindDiscoverWeekly
+	case isListedID(config.ReleaseRadarIDs, simplePlaylist.ID):
+		return config.PlaylistKindReleaseRadar
+	case utils.ContainsFold(config.DiscoverWeeklyNames, simplePlaylist.Name):
+		return config.PlaylistKindDiscoverWeekly
+	case utils.ContainsFold(config.ReleaseRadarNames, simplePlaylist.Name):
+		return config.PlaylistKindReleaseRadar
 	}
 
-	for _, playlist := range filterByPatternWithIgnored(simplePlaylists, pattern) {
-		if cachedPlaylist, isCached := findPlaylist(cachedPlaylists, func(p Playlist) bool {
-			return p.SnapshotID == playlist.SnapshotID
-		}); isCached {
-			playlist = cachedPlaylist
+	return ""
+}
+
+func isListedID(values []string, id spotify.ID) bool {
+	for _, value := range values {
+		if utils.ParseSpotifyID(value) == id {
+			return true
 		}
+	}
 
-		if !playlist.TracksPopulated {
-			tracks, err := listTracks(client, user, playlist.SimplePlaylist)
-			if err != nil {
-				return playlists, err
-			}
+	return false
+}
 
-			playlist.Tracks = tracks
-			playlist.TracksPopulated = true
-		}
+func GetPlaylistsMatchingPattern(
+	client spotify.Client,
+	user *spotify.User,
+	simplePlaylists []spotify.SimplePlaylist,
+	pattern string,
+) ([]Playlist, error) {
+	return getWithCache(client, user, filterByPatternWithIgnored(simplePlaylists, pattern), config.CacheFilename)
+}
 
-		playlists = append(playlists, playlist)
-	}
+// GetFollowedPlaylists picks the playlists followed, but not owned, by the
+// user which aren't generated by Spotify nor already matched by the pattern.
+func GetFollowedPlaylists(
+	client spotify.Client,
+	user *spotify.User,
+	simplePlaylists []spotify.SimplePlaylist,
+	pattern string,
+) ([]Playlist, error) {
+	followed := []Playlist{}
+	re := regexp.MustCompile(pattern)
 
-	if err := cache.WriteCache(config.CacheFilename, playlists); err != nil {
-		return playlists, err
+	for _, simplePlaylist := range simplePlaylists {
+		isOwned := simplePlaylist.Owner.ID == user.ID
+
+		if isOwned || GetKind(simplePlaylist) != "" || re.MatchString(simplePlaylist.Name) {
+			continue
+		}
+
+		followed = append(followed, CreatePlaylist(simplePlaylist))
 	}
 
-	return playlists, nil
+	return getWithCache(client, user, followed, config.FollowedPlaylistCacheFilename)
 }
 
 func GetDiscoveryPlaylists(client spotify.Client, user *spotify.User) ([]Playlist, error) {
 	discoveryPlaylists := []Playlist{}
 
-	simplePlaylists, err := listSimplePlaylists(client, user)
+	simplePlaylists, err := ListSimplePlaylists(client, user)
 	if err != nil {
 		return discoveryPlaylists, err
 	}
 
 	for _, playlist := range simplePlaylists {
-		if _, ok := config.DiscoveryPlaylistNameMap[playlist.Name]; ok {
-			tracks, err := listTracks(client, user, playlist)
+		if GetKind(playlist) != "" {
+			discoveryPlaylist, err := populateTracks(client, user, CreatePlaylist(playlist))
 			if err != nil {
 				return discoveryPlaylists, err
 			}
 
-			discoveryPlaylist := CreatePlaylist(playlist)
+			discoveryPlaylists = append(discoveryPlaylists, discoveryPlaylist)
+		}
+	}
 
-			discoveryPlaylist.Tracks = tracks
-			discoveryPlaylist.TracksPopulated = true
+	sources, err := ParseSources(config.DiscoverySources)
+	if err != nil {
+		return discoveryPlaylists, err
+	}
 
-			discoveryPlaylists = append(discoveryPlaylists, discoveryPlaylist)
+	for _, source := range sources {
+		if _, exists := findPlaylist(discoveryPlaylists, func(p Playlist) bool { return p.ID == source.ID }); exists {
+			continue
 		}
+
+		sourcePlaylist, err := getSourcePlaylist(client, user, source)
+		if err != nil {
+			return discoveryPlaylists, err
+		}
+
+		discoveryPlaylists = append(discoveryPlaylists, sourcePlaylist)
 	}
 
 	return discoveryPlaylists, nil
 }
 
+// ParseSources parses playlist references given as URIs, URLs or IDs, each
+// optionally followed by =<bonus>, e.g. spotify:playlist:<id>=10.
+func ParseSources(values []string) ([]Source, error) {
+	sources := []Source{}
+
+	for _, value := range values {
+		source := Source{ID: utils.ParseSpotifyID(value)}
+
+		// URLs may contain query parameters, so only a numeric suffix is a bonus
+		if index := strings.LastIndex(value, "="); index != -1 {
+			if bonus, err := strconv.ParseFloat(value[index+1:], 64); err == nil {
+				source = Source{ID: utils.ParseSpotifyID(value[:index]), Bonus: bonus}
+			}
+		}
+
+		if source.ID == "" {
+			return sources, fmt.Errorf("Failed to parse discovery source %s", value)
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+func getSourcePlaylist(client spotify.Client, user *spotify.User, source Source) (Playlist, error) {
+	fullPlaylist, err := client.GetPlaylist(source.ID)
+	if err != nil {
+		return Playlist{}, fmt.Errorf("Failed to get discovery source playlist %s: %v", source.ID, err)
+	}
+
+	sourcePlaylist, err := populateTracks(client, user, CreatePlaylist(fullPlaylist.SimplePlaylist))
+	if err != nil {
+		return sourcePlaylist, err
+	}
+
+	sourcePlaylist.ScoreBonus = source.Bonus
+
+	return sourcePlaylist, nil
+}
+
 func FlattenTracks(playlists []Playlist) []spotify.FullTrack {
 	tracks := []spotify.FullTrack{}
 
@@ -124,7 +216,7 @@ func SetRemotePlaylist(
 	remotePlaylist := Playlist{}
 	var err error
 
-	playlists, err := listSimplePlaylists(client, user)
+	playlists, err := ListSimplePlaylists(client, user)
 	if err != nil {
 		return remotePlaylist, err
 	}
@@ -150,15 +242,53 @@ func SetRemotePlaylist(
 	return addTracks(client, remotePlaylist, tracks)
 }
 
-func FindPlaylistByTrack(playlists []Playlist, track spotify.FullTrack) (Playlist, bool) {
-	return findPlaylist(playlists, func(playlist Playlist) bool {
-		trackMap := fulltrack.CreateMap(playlist.Tracks)
+func getWithCache(
+	client spotify.Client,
+	user *spotify.User,
+	candidates []Playlist,
+	cacheFilename string,
+) ([]Playlist, error) {
+	playlists := []Playlist{}
+	cachedPlaylists := []Playlist{}
+	var err error
 
-		return fulltrack.InMap(trackMap, track)
-	})
+	if err := cache.ReadCache(cacheFilename, &cachedPlaylists); err != nil {
+		return playlists, err
+	}
+
+	for _, playlist := range candidates {
+		if cachedPlaylist, isCached := findPlaylist(cachedPlaylists, func(p Playlist) bool {
+			return p.SnapshotID == playlist.SnapshotID
+		}); isCached {
+			playlist = cachedPlaylist
+		} else if previousPlaylist, isPrevious := findPlaylist(cachedPlaylists, func(p Playlist) bool {
+			return p.ID == playlist.ID && p.TracksPopulated
+		}); isPrevious {
+			playlist, err = syncTracks(client, user, playlist, previousPlaylist)
+			if err != nil {
+				return playlists, err
+			}
+		}
+
+		// Playlists cached before added-at dates were stored need to be refetched
+		if !playlist.TracksPopulated || len(playlist.TrackAddedAt) != len(playlist.Tracks) {
+			playlist, err = populateTracks(client, user, playlist)
+			if err != nil {
+				return playlists, err
+			}
+		}
+
+		playlists = append(playlists, playlist)
+	}
+
+	if err := cache.WriteCache(cacheFilename, playlists); err != nil {
+		return playlists, err
+	}
+
+	return playlists, nil
 }
 
-func listSimplePlaylists(client spotify.Client, user *spotify.User) ([]spotify.SimplePlaylist, error) {
+func ListSimplePlaylists(client spotify.Client, user *spotify.User) ([]spotify.SimplePlaylist, error) {
 	pageLimit := 50
 	totalCount := -1
 	playlists := []spotify.SimplePlaylist{}
@@ -187,15 +317,104 @@ func listSimplePlaylists(client spotify.Client, user *spotify.User) ([]spotify.S
 	return playlists, nil
 }
 
+func populateTracks(client spotify.Client, user *spotify.User, playlist Playlist) (Playlist, error) {
+	playlistTracks, err := listTracks(client, user, playlist.SimplePlaylist, "")
+	if err != nil {
+		return playlist, err
+	}
+
+	playlist.Tracks = []spotify.FullTrack{}
+	playlist.TrackAddedAt = []time.Time{}
+
+	for _, playlistTrack := range playlistTracks {
+		// Very old playlists may not have the added at date, leaving it as a zero time
+		addedAt, _ := time.Parse(spotify.TimestampLayout, playlistTrack.AddedAt)
+
+		playlist.Tracks = append(playlist.Tracks, playlistTrack.Track)
+		playlist.TrackAddedAt = append(playlist.TrackAddedAt, addedAt)
+	}
+
+	playlist.TracksPopulated = true
+
+	return playlist, nil
+}
+
+func syncTracks(client spotify.Client, user *spotify.User, playlist Playlist, previous Playlist) (Playlist, error) {
+	playlistTracks, err := listTracks(client, user, playlist.SimplePlaylist, trackReferenceFields)
+	if err != nil {
+		return playlist, err
+	}
+
+	knownTracks := map[spotify.ID]spotify.FullTrack{}
+	for _, track := range previous.Tracks {
+		knownTracks[track.ID] = track
+	}
+
+	unknownIDs := []spotify.ID{}
+	isListed := map[spotify.ID]bool{}
+
+	for _, playlistTrack := range playlistTracks {
+		id := playlistTrack.Track.ID
+
+		// Local files don't have IDs, so they can't be looked up separately
+		if id == "" {
+			return populateTracks(client, user, playlist)
+		}
+
+		if _, isKnown := knownTracks[id]; !isKnown && !isListed[id] {
+			isListed[id] = true
+			unknownIDs = append(unknownIDs, id)
+		}
+	}
+
+	fetchedTracks, err := fulltrack.GetMany(client, unknownIDs)
+	if err != nil {
+		return playlist, err
+	}
+
+	for _, track := range fetchedTracks {
+		knownTracks[track.ID] = track
+	}
+
+	playlist.Tracks = []spotify.FullTrack{}
+	playlist.TrackAddedAt = []time.Time{}
+
+	for _, playlistTrack := range playlistTracks {
+		track, exists := knownTracks[playlistTrack.Track.ID]
+		if !exists {
+			logrus.Warnf("Somehow missed the track for ID %v", playlistTrack.Track.ID)
+
+			continue
+		}
+
+		addedAt, _ := time.Parse(spotify.TimestampLayout, playlistTrack.AddedAt)
+
+		playlist.Tracks = append(playlist.Tracks, track)
+		playlist.TrackAddedAt = append(playlist.TrackAddedAt, addedAt)
+	}
+
+	playlist.TracksPopulated = true
+
+	logrus.Infof(
+		"Synced playlist %s, fetched %d of %d track(s)",
+		playlist.Name,
+		len(unknownIDs),
+		len(playlist.Tracks),
+	)
+
+	return playlist, nil
+}
+
 func listTracks(
 	client spotify.Client,
 	user *spotify.User,
 	simplePlaylist spotify.SimplePlaylist,
-) ([]spotify.FullTrack, error) {
+	fields string,
+) ([]spotify.PlaylistTrack, error) {
 	pageLimit := 100
 	totalCount := -1
 	totalAttempts := 0
-	tracks := []spotify.FullTrack{}
+	tracks := []spotify.PlaylistTrack{}
 	var maxAttempts int
 
 	logrus.Debugf("Listing tracks for playlist %s", simplePlaylist.Name)
@@ -209,7 +428,7 @@ func listTracks(
 		page, err := client.GetPlaylistTracksOpt(
 			simplePlaylist.ID,
 			options,
-			"",
+			fields,
 		)
 		if err != nil {
 			errorMessage := "Failed to get playlist track for simple playlist %s: %v"
@@ -232,9 +451,7 @@ func listTracks(
 			break
 		}
 
-		for _, track := range page.Tracks {
-			tracks = append(tracks, track.Track)
-		}
+		tracks = append(tracks, page.Tracks...)
 	}
 
 	logrus.Infof(
@@ -264,8 +481,7 @@ func filterByPatternWithIgnored(
 	re := regexp.MustCompile(pattern)
 
 	for _, simplePlaylist := range simplePlaylists {
-		if simplePlaylist.Name == config.DiscoverWeeklyName ||
-			simplePlaylist.Name == config.ReleaseRadarName {
+		if GetKind(simplePlaylist) != "" {
 			continue
 		}
 
@@ -312,28 +528,31 @@ func createPlaylist(client spotify.Client, user *spotify.User, name string) (spo
 }
 
 func truncatePlaylist(client spotify.Client, user *spotify.User, playlist Playlist) (Playlist, error) {
-	tracks := playlist.Tracks
 	var err error
 
 	if !playlist.TracksPopulated {
-		tracks, err = listTracks(client, user, playlist.SimplePlaylist)
+		playlist, err = populateTracks(client, user, playlist)
 		if err != nil {
 			return playlist, err
 		}
 	}
 
-	if len(tracks) == 0 {
+	if len(playlist.Tracks) == 0 {
 		return playlist, nil
 	}
 
 	playlist.SnapshotID, err = client.RemoveTracksFromPlaylist(
 		playlist.ID,
-		utils.GetSpotifyIDs(tracks)...,
+		utils.GetSpotifyIDs(playlist.Tracks)...,
 	)
 	if err != nil {
 		return playlist, fmt.Errorf("Failed to truncate playlist %s: %v", playlist.Name, err)
 	}
 
+	playlist.Tracks = []spotify.FullTrack{}
+	playlist.TrackAddedAt = []time.Time{}
+	playlist.TracksPopulated = false
+
 	logrus.Infof("Successfully truncated playlist %s", playlist.Name)
 
 	return playlist, nil
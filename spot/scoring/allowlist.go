@@ -0,0 +1,38 @@
+package scoring
+
+import (
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/filterlist"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+)
+
+const AllowlistComponentName = "allowlist"
+
+func init() {
+	RegisterFixed(AllowlistComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		lists, err := filterlist.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		return allowlist{lists: lists}, nil
+	})
+}
+
+type allowlist struct {
+	lists filterlist.Lists
+}
+
+func (c allowlist) Name() string {
+	return AllowlistComponentName
+}
+
+func (c allowlist) Score(candidate Candidate) float64 {
+	if _, isAllowed := c.lists.IsAllowed(candidate.Track, candidate.Album); isAllowed {
+		return config.AllowlistBoost
+	}
+
+	return 0
+}
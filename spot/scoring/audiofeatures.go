@@ -0,0 +1,72 @@
+package scoring
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/audiofeature"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+const AudioFeaturesComponentName = "audio-features"
+
+func init() {
+	Register(AudioFeaturesComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		logrus.Info("Fetching audio features of the library tracks")
+
+		features, err := audiofeature.GetMany(client, utils.GetSpotifyIDs(library.Tracks))
+		if err != nil {
+			return nil, err
+		}
+
+		libraryFeatures := []spotify.AudioFeatures{}
+		for _, feature := range features {
+			libraryFeatures = append(libraryFeatures, feature)
+		}
+
+		return audioFeatures{
+			client:   client,
+			profile:  audiofeature.CreateProfile(libraryFeatures),
+			features: map[spotify.ID]spotify.AudioFeatures{},
+		}, nil
+	})
+}
+
+type audioFeatures struct {
+	client   spotify.Client
+	profile  audiofeature.Profile
+	features map[spotify.ID]spotify.AudioFeatures
+}
+
+func (c audioFeatures) Name() string {
+	return AudioFeaturesComponentName
+}
+
+func (c audioFeatures) Prepare(candidates []Candidate) error {
+	ids := []spotify.ID{}
+
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.Track.ID)
+	}
+
+	features, err := audiofeature.GetMany(c.client, ids)
+	if err != nil {
+		return err
+	}
+
+	for id, feature := range features {
+		c.features[id] = feature
+	}
+
+	return nil
+}
+
+func (c audioFeatures) Score(candidate Candidate) float64 {
+	feature, exists := c.features[candidate.Track.ID]
+	if !exists {
+		return 0
+	}
+
+	return -c.profile.GetDistance(feature)
+}
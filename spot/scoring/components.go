@@ -0,0 +1,153 @@
+package scoring
+
+import (
+	"math"
+	"strings"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/playlist"
+	"github.com/kristofferostlund/spot/spot/spotifytrack"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+)
+
+const (
+	ReleaseYearComponentName      = "release-year"
+	ArtistAffinityComponentName   = "artist-affinity"
+	WordPenaltyComponentName      = "word-penalty"
+	FavouredPlaylistComponentName = "favoured-playlist"
+	HistoryComponentName          = "suggestion-history"
+	ProvenanceComponentName       = "provenance"
+	SourceBonusComponentName      = "source-bonus"
+)
+
+func init() {
+	Register(ReleaseYearComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return releaseYear{}, nil
+	})
+
+	Register(ArtistAffinityComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return artistAffinity{tracksByArtist: library.ArtistGroups}, nil
+	})
+
+	Register(WordPenaltyComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return wordPenalty{}, nil
+	})
+
+	Register(FavouredPlaylistComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return favouredPlaylist{}, nil
+	})
+
+	Register(HistoryComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return history{}, nil
+	})
+
+	Register(ProvenanceComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return provenance{}, nil
+	})
+
+	Register(SourceBonusComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return sourceBonus{}, nil
+	})
+}
+
+// releaseYear is superseded by freshness but kept for stored profiles using it.
+type releaseYear struct{}
+
+func (c releaseYear) Name() string {
+	return ReleaseYearComponentName
+}
+
+func (c releaseYear) Score(candidate Candidate) float64 {
+	return float64(candidate.Album.ReleaseDateTime().Year() - 2000)
+}
+
+type artistAffinity struct {
+	tracksByArtist spotifytrack.ArtistFullTrackMap
+}
+
+func (c artistAffinity) Name() string {
+	return ArtistAffinityComponentName
+}
+
+func (c artistAffinity) Score(candidate Candidate) float64 {
+	return float64(fulltrack.GetTrackCountByArtists(c.tracksByArtist, candidate.Track.Artists))
+}
+
+type wordPenalty struct{}
+
+func (c wordPenalty) Name() string {
+	return WordPenaltyComponentName
+}
+
+func (c wordPenalty) Score(candidate Candidate) float64 {
+	score := 0
+
+	for word, penalty := range config.WordPenaltyMap {
+		if strings.Contains(strings.ToLower(candidate.Track.Name), word) {
+			score += penalty
+		}
+	}
+
+	return float64(score)
+}
+
+type favouredPlaylist struct{}
+
+func (c favouredPlaylist) Name() string {
+	return FavouredPlaylistComponentName
+}
+
+func (c favouredPlaylist) Score(candidate Candidate) float64 {
+	for _, source := range append([]playlist.Playlist{candidate.Playlist}, candidate.Sources...) {
+		if source.Kind == config.FavouredPlaylistKind {
+			return float64(config.FavouredPlaylistAddedScore)
+		}
+	}
+
+	return 0
+}
+
+type history struct{}
+
+func (c history) Name() string {
+	return HistoryComponentName
+}
+
+func (c history) Score(candidate Candidate) float64 {
+	return -float64(candidate.PreviousSuggestionCount)
+}
+
+type provenance struct{}
+
+func (c provenance) Name() string {
+	return ProvenanceComponentName
+}
+
+// Score counts the sources beyond the first one, as a track surfacing in
+// several discovery playlists is a stronger hint than one appearing once.
+func (c provenance) Score(candidate Candidate) float64 {
+	if len(candidate.Sources) < 2 {
+		return 0
+	}
+
+	return float64(len(candidate.Sources) - 1)
+}
+
+type sourceBonus struct{}
+
+func (c sourceBonus) Name() string {
+	return SourceBonusComponentName
+}
+
+func (c sourceBonus) Score(candidate Candidate) float64 {
+	bonus := candidate.Playlist.ScoreBonus
+
+	for _, source := range candidate.Sources {
+		bonus = math.Max(bonus, source.ScoreBonus)
+	}
+
+	return bonus
+}
@@ -0,0 +1,68 @@
+package scoring
+
+import (
+	"math"
+	"time"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+)
+
+const FreshnessComponentName = "freshness"
+
+func init() {
+	Register(FreshnessComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		return freshness{runDate: time.Now(), halfLifeDays: config.FreshnessHalfLifeDays}, nil
+	})
+}
+
+type freshness struct {
+	runDate      time.Time
+	halfLifeDays float64
+}
+
+func (c freshness) Name() string {
+	return FreshnessComponentName
+}
+
+// Score decays from FreshnessMaxScore on the run date, halving every
+// halfLifeDays, so the score of a release only depends on its age.
+func (c freshness) Score(candidate Candidate) float64 {
+	if candidate.Album.ReleaseDate == "" || c.halfLifeDays <= 0 {
+		return 0
+	}
+
+	ageDays := c.runDate.Sub(c.getReleaseDate(candidate.Album)).Hours() / 24
+	if ageDays < 0 {
+		ageDays = 0
+	}
+
+	return config.FreshnessMaxScore * math.Pow(0.5, ageDays/c.halfLifeDays)
+}
+
+// getReleaseDate uses the middle of the period a release date with year or
+// month precision covers, without going past the run date, as a release in
+// "2018" is as likely to be from January as from December.
+func (c freshness) getReleaseDate(album spotify.FullAlbum) time.Time {
+	start := album.ReleaseDateTime()
+	end := start
+
+	switch album.ReleaseDatePrecision {
+	case "year":
+		end = start.AddDate(1, 0, 0)
+	case "month":
+		end = start.AddDate(0, 1, 0)
+	}
+
+	if end.After(c.runDate) {
+		end = c.runDate
+	}
+
+	if !end.After(start) {
+		return start
+	}
+
+	return start.Add(end.Sub(start) / 2)
+}
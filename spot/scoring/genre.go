@@ -0,0 +1,63 @@
+package scoring
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/artistgenre"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+)
+
+const GenreComponentName = "genre"
+
+func init() {
+	Register(GenreComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		logrus.Info("Fetching genres of the library artists")
+
+		genres, err := artistgenre.GetMany(client, artistgenre.GetArtistIDs(library.Tracks))
+		if err != nil {
+			return nil, err
+		}
+
+		return genre{
+			client:  client,
+			profile: artistgenre.CreateProfile(library.Tracks, genres),
+			genres:  genres,
+		}, nil
+	})
+}
+
+type genre struct {
+	client  spotify.Client
+	profile artistgenre.Profile
+	genres  map[spotify.ID][]string
+}
+
+func (c genre) Name() string {
+	return GenreComponentName
+}
+
+func (c genre) Prepare(candidates []Candidate) error {
+	tracks := []spotify.FullTrack{}
+
+	for _, candidate := range candidates {
+		tracks = append(tracks, candidate.Track)
+	}
+
+	genres, err := artistgenre.GetMany(c.client, artistgenre.GetArtistIDs(tracks))
+	if err != nil {
+		return err
+	}
+
+	for id, artistGenres := range genres {
+		c.genres[id] = artistGenres
+	}
+
+	return nil
+}
+
+func (c genre) Score(candidate Candidate) float64 {
+	genres := artistgenre.GetTrackGenres(candidate.Track, c.genres)
+
+	return 100 * c.profile.GetOverlap(genres)
+}
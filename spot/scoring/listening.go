@@ -0,0 +1,37 @@
+package scoring
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/listening"
+)
+
+const ListeningAffinityComponentName = "listening-affinity"
+
+func init() {
+	Register(ListeningAffinityComponentName, func(client spotify.Client, library libraryindex.Index) (Component, error) {
+		logrus.Info("Fetching top artists and recently played tracks")
+
+		profile, err := listening.GetProfile(client)
+		if err != nil {
+			// Listening history requires the redirect flow, so it's not an error to go without it
+			logrus.Warnf("Scoring without listening affinity: %v", err)
+		}
+
+		return listeningAffinity{profile: profile}, nil
+	})
+}
+
+type listeningAffinity struct {
+	profile listening.Profile
+}
+
+func (c listeningAffinity) Name() string {
+	return ListeningAffinityComponentName
+}
+
+func (c listeningAffinity) Score(candidate Candidate) float64 {
+	return 100 * c.profile.GetAffinity(candidate.Track.Artists)
+}
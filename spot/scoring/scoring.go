@@ -0,0 +1,212 @@
+package scoring
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/playlist"
+)
+
+type Candidate struct {
+	Track                   spotify.FullTrack
+	Album                   spotify.FullAlbum
+	Playlist                playlist.Playlist
+	Sources                 []playlist.Playlist
+	PreviousSuggestionCount int
+}
+
+type Component interface {
+	Name() string
+	Score(candidate Candidate) float64
+}
+
+// Preparer is implemented by components needing to look at every candidate
+// before scoring, which allows them to fetch their data in batches.
+type Preparer interface {
+	Prepare(candidates []Candidate) error
+}
+
+type Factory func(client spotify.Client, library libraryindex.Index) (Component, error)
+
+type Profile map[string]float64
+
+type Scorer interface {
+	Prepare(candidates []Candidate) error
+	Score(candidate Candidate) Result
+}
+
+type Contribution struct {
+	Name         string  `json:"name"`
+	Raw          float64 `json:"raw"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+}
+
+type Result struct {
+	Total         float64
+	Contributions []Contribution
+}
+
+type WeightedScorer struct {
+	components []weightedComponent
+}
+
+type weightedComponent struct {
+	component Component
+	weight    float64
+}
+
+var factories = map[string]Factory{}
+
+// fixedFactories are always applied with a weight of 1, regardless of the
+// profile, and are therefore never learned.
+var fixedFactories = map[string]Factory{}
+
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+func RegisterFixed(name string, factory Factory) {
+	fixedFactories[name] = factory
+}
+
+func IsFixed(name string) bool {
+	_, isFixed := fixedFactories[name]
+
+	return isFixed
+}
+
+func GetComponentNames() []string {
+	names := []string{}
+
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func ReadProfile(fileName, name string) (Profile, error) {
+	profiles := map[string]Profile{}
+
+	if err := cache.ReadCache(fileName, &profiles); err != nil {
+		return Profile{}, err
+	}
+
+	if profile, exists := profiles[name]; exists {
+		return profile, nil
+	}
+
+	if name == config.DefaultScoringProfileName {
+		return Profile(config.DefaultScoringProfile), nil
+	}
+
+	return Profile{}, fmt.Errorf("Failed to find scoring profile %s in %s", name, fileName)
+}
+
+func WriteProfile(fileName, name string, profile Profile) error {
+	profiles := map[string]Profile{}
+
+	if err := cache.ReadCache(fileName, &profiles); err != nil {
+		return err
+	}
+
+	profiles[name] = profile
+
+	return cache.WriteCache(fileName, profiles)
+}
+
+func CreateScorer(client spotify.Client, library libraryindex.Index, profile Profile) (Scorer, error) {
+	scorer := WeightedScorer{components: []weightedComponent{}}
+	names := []string{}
+
+	for name := range profile {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if IsFixed(name) {
+			logrus.Debugf("Ignoring the weight of %s as it's always applied", name)
+
+			continue
+		}
+
+		factory, exists := factories[name]
+		if !exists {
+			return scorer, fmt.Errorf("Unknown scoring component %s, available: %v", name, GetComponentNames())
+		}
+
+		if profile[name] == 0 {
+			continue
+		}
+
+		component, err := factory(client, library)
+		if err != nil {
+			return scorer, fmt.Errorf("Failed to create scoring component %s: %v", name, err)
+		}
+
+		scorer.components = append(scorer.components, weightedComponent{
+			component: component,
+			weight:    profile[name],
+		})
+	}
+
+	fixedNames := []string{}
+	for name := range fixedFactories {
+		fixedNames = append(fixedNames, name)
+	}
+
+	sort.Strings(fixedNames)
+
+	for _, name := range fixedNames {
+		component, err := fixedFactories[name](client, library)
+		if err != nil {
+			return scorer, fmt.Errorf("Failed to create scoring component %s: %v", name, err)
+		}
+
+		scorer.components = append(scorer.components, weightedComponent{component: component, weight: 1})
+	}
+
+	return scorer, nil
+}
+
+func (s WeightedScorer) Prepare(candidates []Candidate) error {
+	for _, weighted := range s.components {
+		if preparer, ok := weighted.component.(Preparer); ok {
+			if err := preparer.Prepare(candidates); err != nil {
+				return fmt.Errorf("Failed to prepare scoring component %s: %v", weighted.component.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s WeightedScorer) Score(candidate Candidate) Result {
+	result := Result{Contributions: []Contribution{}}
+
+	for _, weighted := range s.components {
+		raw := weighted.component.Score(candidate)
+		contribution := Contribution{
+			Name:         weighted.component.Name(),
+			Raw:          raw,
+			Weight:       weighted.weight,
+			Contribution: raw * weighted.weight,
+		}
+
+		result.Total += contribution.Contribution
+		result.Contributions = append(result.Contributions, contribution)
+	}
+
+	return result
+}
@@ -5,16 +5,28 @@ import (
 	"math"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zmb3/spotify"
 
+	"github.com/kristofferostlund/spot/spot/artistcrawl"
+	"github.com/kristofferostlund/spot/spot/candidatefilter"
 	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/feedback"
+	"github.com/kristofferostlund/spot/spot/filterlist"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/librarysource"
+	"github.com/kristofferostlund/spot/spot/librarystore"
+	"github.com/kristofferostlund/spot/spot/listening"
+	"github.com/kristofferostlund/spot/spot/newrelease"
 	"github.com/kristofferostlund/spot/spot/playlist"
+	"github.com/kristofferostlund/spot/spot/scoring"
 	"github.com/kristofferostlund/spot/spot/spotifyrecommendation"
 	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
 	"github.com/kristofferostlund/spot/spot/spotifyuser"
 	"github.com/kristofferostlund/spot/spot/suggestion"
+	"github.com/kristofferostlund/spot/spot/suggestionlog"
 	"github.com/kristofferostlund/spot/spot/utils"
 )
 
@@ -22,6 +34,7 @@ type State struct {
 	User      *spotify.User
 	Playlists []playlist.Playlist
 	Tracks    []spotify.FullTrack
+	Library   libraryindex.Index
 }
 
 type Discovery struct {
@@ -32,6 +45,13 @@ type Discovery struct {
 	Suggestions        []suggestion.Suggestion
 }
 
+type NewReleases struct {
+	User        *spotify.User
+	Playlists   []playlist.Playlist
+	Tracks      []spotify.FullTrack
+	Suggestions []suggestion.Suggestion
+}
+
 type Recommendation struct {
 	User        *spotify.User
 	Playlists   []playlist.Playlist
@@ -48,6 +68,26 @@ func Run(client spotify.Client) {
 	case config.OperationTypeRecommendations:
 		recommend(client)
 
+		break
+	case config.OperationTypeNewReleases:
+		scanNewReleases(client)
+
+		break
+	case config.OperationTypeRelatedArtists:
+		crawlRelatedArtists(client)
+
+		break
+	case config.OperationTypeListeningReport:
+		reportListening(client)
+
+		break
+	case config.OperationTypeArchive:
+		archiveDiscoveryPlaylists(client)
+
+		break
+	case config.OperationTypeRadio:
+		playRadio(client)
+
 		break
 	case config.OperationTypeCheckTrackExists:
 		checkTrackExists(client)
@@ -56,6 +96,38 @@ func Run(client spotify.Client) {
 	case config.OperationTypeCheckPlaylistHoles:
 		checkPlaylistHoles(client)
 
+		break
+	case config.OperationTypeSync:
+		syncLibrary(client)
+
+		break
+	case config.OperationTypeLearnWeights:
+		learnWeights()
+
+		break
+	case config.OperationTypeBlockAdd:
+		editList(filterlist.ListBlock, true)
+
+		break
+	case config.OperationTypeBlockRemove:
+		editList(filterlist.ListBlock, false)
+
+		break
+	case config.OperationTypeAllowAdd:
+		editList(filterlist.ListAllow, true)
+
+		break
+	case config.OperationTypeAllowRemove:
+		editList(filterlist.ListAllow, false)
+
+		break
+	case config.OperationTypeBlockCurrentArtist:
+		blockCurrentArtist(client)
+
+		break
+	case config.OperationTypeShowLists:
+		showLists()
+
 		break
 	default:
 		logrus.Errorf("Operation type %s is not a valid operation type", config.OperationType)
@@ -98,9 +170,16 @@ func checkTrackExists(client spotify.Client) {
 		return
 	}
 
-	foundPlaylist, exists := playlist.FindPlaylistByTrack(state.Playlists, currentTrack)
-	if exists {
-		logrus.Infof("The track already on playlist %s", foundPlaylist.Name)
+	occurrences := state.Library.Find(currentTrack)
+	if len(occurrences) > 0 {
+		for _, occurrence := range occurrences {
+			logrus.Infof(
+				"The track is already on playlist %s at position %d, added %s",
+				occurrence.PlaylistName,
+				occurrence.Position+1,
+				occurrence.AddedAt.Format("2006-01-02"),
+			)
+		}
 
 		return
 	}
@@ -108,6 +187,103 @@ func checkTrackExists(client spotify.Client) {
 	logrus.Infof("The track is new, quite amazing I'd say!")
 }
 
+func editList(list string, add bool) {
+	lists, err := filterlist.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	entry, err := filterlist.CreateEntry(config.ListEntryKind, config.ListEntryValue, config.ListEntryNote)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	if add {
+		err = lists.Add(list, entry)
+	} else {
+		var removed bool
+
+		removed, err = lists.Remove(list, entry)
+		if err == nil && !removed {
+			logrus.Warnf("Found no %s entry %s in the %s list", entry.Kind, entry.Value, list)
+		}
+	}
+
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	if err := filterlist.Write(lists); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	fmt.Printf("\n%s\n", filterlist.CreatePrintableTable(lists))
+}
+
+func blockCurrentArtist(client spotify.Client) {
+	status, err := client.PlayerCurrentlyPlaying()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	if !status.Playing || status.Item == nil || len(status.Item.Artists) == 0 {
+		logrus.Warn("User doesn't seem to listen to spotify currently")
+
+		return
+	}
+
+	artist := status.Item.Artists[0]
+
+	lists, err := filterlist.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	entry, err := filterlist.CreateEntry(filterlist.KindArtist, string(artist.ID), artist.Name)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	if err := lists.Add(filterlist.ListBlock, entry); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	if err := filterlist.Write(lists); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	logrus.Infof("Blocked %s, it won't be suggested again", artist.Name)
+}
+
+func showLists() {
+	lists, err := filterlist.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	fmt.Printf("\n%s\n", filterlist.CreatePrintableTable(lists))
+}
+
 func checkPlaylistHoles(client spotify.Client) {
 	numbers := []int{}
 	holes := []int{}
@@ -119,10 +295,15 @@ func checkPlaylistHoles(client spotify.Client) {
 		return
 	}
 
-	for index, list := range state.Playlists {
-		pattern := regexp.MustCompile("Metal 0*(\\d+)")
+	pattern := regexp.MustCompile("Metal 0*(\\d+)")
 
-		numberString := pattern.FindStringSubmatch(list.Name)[1]
+	for _, list := range state.Library.Playlists {
+		match := pattern.FindStringSubmatch(list.Name)
+		if match == nil {
+			continue
+		}
+
+		numberString := match[1]
 		value, err := strconv.Atoi(numberString)
 
 		if err != nil {
@@ -132,8 +313,9 @@ func checkPlaylistHoles(client spotify.Client) {
 		}
 
 		numbers = append(numbers, value)
+		count := len(numbers)
 
-		if index > 0 && math.Abs(float64(numbers[index-1]-value)) != 1.0 {
+		if count > 1 && math.Abs(float64(numbers[count-2]-value)) != 1.0 {
 			holes = append(holes, value+1)
 		}
 	}
@@ -143,6 +325,159 @@ func checkPlaylistHoles(client spotify.Client) {
 	}
 }
 
+func syncLibrary(client spotify.Client) {
+	state, err := getState(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	store, err := librarystore.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	lastSyncedAt := store.LastSyncedAt
+	changes := store.Sync(state.Playlists, time.Now())
+
+	if err := librarystore.Write(store); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	fmt.Printf("\n%s\n", librarystore.CreatePrintableReport(changes, lastSyncedAt))
+
+	suggestionLog, err := suggestionlog.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	accepted := suggestionLog.MarkAccepted(state.Library)
+
+	if err := suggestionlog.Write(suggestionLog); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	for _, entry := range accepted {
+		logrus.Infof("Suggested track %s by %s was added to %s", entry.Name, entry.Artists, entry.AcceptedIn)
+	}
+}
+
+func reportListening(client spotify.Client) {
+	state, err := getState(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	profile, err := listening.GetProfile(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	fmt.Println(listening.CreatePrintableReport(profile, state.Library))
+}
+
+func archiveDiscoveryPlaylists(client spotify.Client) {
+	user, err := getUser(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	discoveryPlaylists, err := playlist.GetDiscoveryPlaylists(client, user)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	store, err := librarystore.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	archivedAt := time.Now()
+	archives := []librarystore.Archive{}
+	isNew := []bool{}
+
+	for _, list := range discoveryPlaylists {
+		archive, archived := store.Archive(list, archivedAt)
+
+		archives = append(archives, archive)
+		isNew = append(isNew, archived)
+
+		if archived && config.CopyArchives {
+			name := fmt.Sprintf(config.SpottedArchivePlaylistBase, list.Name, archivedAt.Format("2006-01-02"))
+
+			createPlaylist(client, user, name, list.Tracks)
+		}
+	}
+
+	if err := librarystore.Write(store); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	fmt.Printf("\n%s\n", librarystore.CreatePrintableArchiveReport(archives, isNew))
+}
+
+func learnWeights() {
+	suggestionLog, err := suggestionlog.Read()
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	profile, err := scoring.ReadProfile(config.ScoringProfilesFilename, config.ScoringProfile)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	decidedBefore := time.Now().AddDate(0, 0, -config.FeedbackMinAgeDays)
+
+	fit, err := feedback.FitWeights(suggestionLog.Entries, profile, decidedBefore)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	fmt.Printf("\n%s\n", feedback.CreatePrintableTable(fit))
+
+	if !config.ApplyLearnedWeights {
+		logrus.Info("Run with -apply to use the learned weights")
+
+		return
+	}
+
+	if err := scoring.WriteProfile(config.ScoringProfilesFilename, config.ScoringProfile, fit.GetProfile()); err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	logrus.Infof("Applied the learned weights to the %s scoring profile", config.ScoringProfile)
+}
+
 func recommend(client spotify.Client) {
 	recommendations, err := getRecommendations(client)
 	if err != nil {
@@ -151,16 +486,95 @@ func recommend(client spotify.Client) {
 		return
 	}
 
-	defer fmt.Printf("\n%s\n", suggestion.CreatePrintableTable(recommendations.Suggestions))
+	recordSuggestions(recommendations.Suggestions)
 
-	if config.OutputType == config.OutputTypePlaylist {
-		createPlaylist(
-			client,
-			recommendations.User,
-			config.SpottedRecommendationsPlaylistName,
-			suggestion.GetTracks(recommendations.Suggestions),
-		)
+	outputSuggestions(
+		client,
+		recommendations.User,
+		config.SpottedRecommendationsPlaylistName,
+		recommendations.Suggestions,
+	)
+}
+
+func scanNewReleases(client spotify.Client) {
+	newReleases, err := getNewReleases(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	recordSuggestions(newReleases.Suggestions)
+
+	outputSuggestions(
+		client,
+		newReleases.User,
+		config.SpottedNewReleasesPlaylistName,
+		newReleases.Suggestions,
+	)
+}
+
+func crawlRelatedArtists(client spotify.Client) {
+	state, err := getState(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	suggestions, err := getRelatedArtistSuggestions(client, state)
+	if err != nil {
+		logrus.Error(err)
+
+		return
 	}
+
+	recordSuggestions(suggestions)
+
+	outputSuggestions(
+		client,
+		state.User,
+		config.SpottedRelatedArtistsPlaylistName,
+		suggestions,
+	)
+}
+
+func playRadio(client spotify.Client) {
+	if config.RadioSize <= 0 {
+		logrus.Errorf("Invalid radio size %d, expected at least one track", config.RadioSize)
+
+		return
+	}
+
+	seedTrack, err := getRadioSeedTrack(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	state, err := getState(client)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	suggestions, err := getRadioSuggestions(client, state, seedTrack)
+	if err != nil {
+		logrus.Error(err)
+
+		return
+	}
+
+	// Radio tracks aren't discovery suggestions, so they're kept out of the
+	// suggestion history and the learned weights
+	outputSuggestions(
+		client,
+		state.User,
+		fmt.Sprintf(config.SpottedRadioPlaylistBase, seedTrack.Name, time.Now().Format("2006-01-02")),
+		suggestions,
+	)
 }
 
 func discover(client spotify.Client) {
@@ -171,15 +585,72 @@ func discover(client spotify.Client) {
 		return
 	}
 
-	defer fmt.Printf("\n%s\n", suggestion.CreatePrintableTable(discovery.Suggestions))
+	recordSuggestions(discovery.Suggestions)
+
+	outputSuggestions(
+		client,
+		discovery.User,
+		config.SpottedDiscoveryPlaylistName,
+		discovery.Suggestions,
+	)
+}
+
+func outputSuggestions(
+	client spotify.Client,
+	user *spotify.User,
+	playlistName string,
+	suggestions []suggestion.Suggestion,
+) {
+	if config.OutputType == config.OutputTypeJSON {
+		output, err := suggestion.CreateJSON(suggestions)
+		if err != nil {
+			logrus.Error(err)
+
+			return
+		}
+
+		fmt.Println(output)
+
+		return
+	}
+
+	defer fmt.Printf("\n%s\n", suggestion.CreatePrintableTable(suggestions))
 
 	if config.OutputType == config.OutputTypePlaylist {
-		createPlaylist(
-			client,
-			discovery.User,
-			config.SpottedDiscoveryPlaylistName,
-			suggestion.GetTracks(discovery.Suggestions),
-		)
+		createPlaylist(client, user, playlistName, suggestion.GetTracks(suggestions))
+	}
+}
+
+// recordSuggestions records every suggestion shown, except for reruns of
+// archived weeks as those were recorded when first suggested.
+func recordSuggestions(suggestions []suggestion.Suggestion) {
+	if config.ArchiveDate != "" {
+		return
+	}
+
+	suggestionLog, err := suggestionlog.Read()
+	if err != nil {
+		logrus.Warnf("Failed to read the suggestion log: %v", err)
+
+		return
+	}
+
+	suggestedAt := time.Now()
+	entries := []suggestionlog.Entry{}
+
+	for _, s := range suggestions {
+		source := s.Playlist.Name
+		if source == "" {
+			source = config.OperationType
+		}
+
+		entries = append(entries, suggestionlog.CreateEntry(s.Track, source, s.Relevance, s.Breakdown, suggestedAt))
+	}
+
+	suggestionLog.Record(entries)
+
+	if err := suggestionlog.Write(suggestionLog); err != nil {
+		logrus.Warnf("Failed to write the suggestion log: %v", err)
 	}
 }
 
@@ -201,36 +672,61 @@ func createPlaylist(
 	return
 }
 
+func getUser(client spotify.Client) (*spotify.User, error) {
+	if config.CredentialsFlow == config.CredentialsFlowRedirect {
+		return spotifyuser.GetCurrentUser(client)
+	}
+
+	return spotifyuser.GetPublicProfile(client, config.UserName)
+}
+
 func getState(client spotify.Client) (State, error) {
 	state := State{}
 	var err error
 
-	if config.CredentialsFlow == config.CredentialsFlowRedirect {
-		state.User, err = spotifyuser.GetCurrentUser(client)
-	} else {
-		state.User, err = spotifyuser.GetPublicProfile(client, config.UserName)
-	}
-
+	state.User, err = getUser(client)
 	if err != nil {
 		return state, err
 	}
 
 	logrus.Infof("Fetching playlists of user %s", state.User.ID)
 
-	state.Playlists, err = playlist.GetPlaylistsMatchingPattern(
-		client,
-		state.User,
-		config.PlaylistNamePattern,
-	)
+	state.Playlists = []playlist.Playlist{}
+	simplePlaylists := []spotify.SimplePlaylist{}
+
+	// Both the matching and followed playlists are picked from the same listing
+	if librarysource.IsEnabled(config.LibrarySourcePlaylists) ||
+		librarysource.IsEnabled(config.LibrarySourceFollowedPlaylists) {
+		simplePlaylists, err = playlist.ListSimplePlaylists(client, state.User)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	if librarysource.IsEnabled(config.LibrarySourcePlaylists) {
+		state.Playlists, err = playlist.GetPlaylistsMatchingPattern(
+			client,
+			state.User,
+			simplePlaylists,
+			config.PlaylistNamePattern,
+		)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	sources, err := librarysource.GetSources(client, state.User, simplePlaylists)
 	if err != nil {
 		return state, err
 	}
 
-	state.Tracks = fulltrack.GetUnique(playlist.FlattenTracks(state.Playlists))
+	state.Library = libraryindex.Create(state.Playlists, sources)
+	state.Tracks = state.Library.Tracks
 
 	logrus.Infof(
-		"Playlist count: %3d, total track count: %3d",
+		"Playlist count: %3d, other source count: %3d, total track count: %3d",
 		len(state.Playlists),
+		len(sources),
 		len(state.Tracks),
 	)
 
@@ -253,19 +749,79 @@ func getDiscovery(client spotify.Client) (Discovery, error) {
 		Tracks:    state.Tracks,
 	}
 
-	discovery.DiscoveryPlaylists, err = playlist.GetDiscoveryPlaylists(client, discovery.User)
+	if config.ArchiveDate != "" {
+		discovery.DiscoveryPlaylists, err = getArchivedDiscoveryPlaylists(client)
+	} else {
+		discovery.DiscoveryPlaylists, err = playlist.GetDiscoveryPlaylists(client, discovery.User)
+	}
+
+	if err != nil {
+		return discovery, err
+	}
+
+	scorer, err := getScorer(client, state.Library)
+	if err != nil {
+		return discovery, err
+	}
+
+	filters, history, err := getFilters(client)
 	if err != nil {
 		return discovery, err
 	}
 
-	discovery.Suggestions, err = suggestion.GetSuggestions(client, discovery.DiscoveryPlaylists, discovery.Tracks)
+	discovery.Suggestions, err = suggestion.GetSuggestions(
+		client,
+		discovery.DiscoveryPlaylists,
+		state.Library,
+		scorer,
+		filters,
+		history,
+	)
 	if err != nil {
 		return discovery, err
 	}
 
+	filters.LogSummary()
+
 	return discovery, nil
 }
 
+func getArchivedDiscoveryPlaylists(client spotify.Client) ([]playlist.Playlist, error) {
+	playlists := []playlist.Playlist{}
+
+	date, err := time.ParseInLocation("2006-01-02", config.ArchiveDate, time.Local)
+	if err != nil {
+		return playlists, fmt.Errorf("Failed to parse the archive date %s: %v", config.ArchiveDate, err)
+	}
+
+	store, err := librarystore.Read()
+	if err != nil {
+		return playlists, err
+	}
+
+	archives := store.GetArchivesAt(date)
+	if len(archives) == 0 {
+		return playlists, fmt.Errorf("Found no discovery playlists archived the week up to %s", config.ArchiveDate)
+	}
+
+	for _, archive := range archives {
+		logrus.Infof(
+			"Using %s as archived %s",
+			archive.PlaylistName,
+			archive.ArchivedAt.Format("2006-01-02 15:04"),
+		)
+
+		restored, err := archive.Restore(client)
+		if err != nil {
+			return playlists, err
+		}
+
+		playlists = append(playlists, restored)
+	}
+
+	return playlists, nil
+}
+
 func getRecommendations(client spotify.Client) (Recommendation, error) {
 	state := State{}
 	recommendations := Recommendation{}
@@ -282,7 +838,21 @@ func getRecommendations(client spotify.Client) (Recommendation, error) {
 		Tracks:    state.Tracks,
 	}
 
-	recommendedTracks, err := spotifyrecommendation.Recommend(client)
+	filters, history, err := getFilters(client)
+	if err != nil {
+		return recommendations, err
+	}
+
+	if config.RecommendationMinReleaseYear > 0 {
+		filters = filters.With(candidatefilter.CreateReleaseYear(config.RecommendationMinReleaseYear))
+	}
+
+	recommendedTracks, err := spotifyrecommendation.Recommend(client, state.Library)
+	if err != nil {
+		return recommendations, err
+	}
+
+	scorer, err := getScorer(client, state.Library)
 	if err != nil {
 		return recommendations, err
 	}
@@ -290,11 +860,229 @@ func getRecommendations(client spotify.Client) (Recommendation, error) {
 	recommendations.Suggestions, err = suggestion.GetSuggestionsFromTracks(
 		client,
 		recommendedTracks,
-		recommendations.Tracks,
+		state.Library,
+		scorer,
+		filters,
+		history,
 	)
 	if err != nil {
 		return recommendations, err
 	}
 
+	filters.LogSummary()
+
 	return recommendations, nil
 }
+
+func getNewReleases(client spotify.Client) (NewReleases, error) {
+	newReleases := NewReleases{}
+
+	state, err := getState(client)
+	if err != nil {
+		return newReleases, err
+	}
+
+	newReleases = NewReleases{
+		User:      state.User,
+		Playlists: state.Playlists,
+		Tracks:    state.Tracks,
+	}
+
+	scanState, err := newrelease.ReadState()
+	if err != nil {
+		return newReleases, err
+	}
+
+	releasedTracks, err := newrelease.Scan(
+		client,
+		state.Library.GetArtistsByTrackCount(),
+		&scanState,
+		time.Now(),
+	)
+	if err != nil {
+		return newReleases, err
+	}
+
+	scorer, err := getScorer(client, state.Library)
+	if err != nil {
+		return newReleases, err
+	}
+
+	filters, history, err := getFilters(client)
+	if err != nil {
+		return newReleases, err
+	}
+
+	// Singles are as much of a release as albums are
+	filters = filters.Without(candidatefilter.AlbumSizeFilterName)
+
+	newReleases.Suggestions, err = suggestion.GetSuggestionsFromTracks(
+		client,
+		releasedTracks,
+		state.Library,
+		scorer,
+		filters,
+		history,
+	)
+	if err != nil {
+		return newReleases, err
+	}
+
+	filters.LogSummary()
+
+	// Previews shouldn't use up the releases before they've reached a playlist
+	if config.OutputType != config.OutputTypePlaylist {
+		return newReleases, nil
+	}
+
+	if err := newrelease.WriteState(scanState); err != nil {
+		return newReleases, err
+	}
+
+	return newReleases, nil
+}
+
+func getRelatedArtistSuggestions(client spotify.Client, state State) ([]suggestion.Suggestion, error) {
+	crawled, err := artistcrawl.Crawl(client, state.Library)
+	if err != nil {
+		return []suggestion.Suggestion{}, err
+	}
+
+	scorer, err := getScorer(client, state.Library)
+	if err != nil {
+		return []suggestion.Suggestion{}, err
+	}
+
+	filters, history, err := getFilters(client)
+	if err != nil {
+		return []suggestion.Suggestion{}, err
+	}
+
+	suggestions, err := suggestion.GetSuggestionsFromPaths(
+		client,
+		crawled.Tracks,
+		crawled.Paths,
+		state.Library,
+		scorer,
+		filters,
+		history,
+	)
+	if err != nil {
+		return suggestions, err
+	}
+
+	filters.LogSummary()
+
+	return suggestions, nil
+}
+
+func getRadioSeedTrack(client spotify.Client) (spotify.FullTrack, error) {
+	if config.RadioSeedTrack != "" {
+		id := utils.ParseSpotifyID(config.RadioSeedTrack)
+		if id == "" {
+			return spotify.FullTrack{}, fmt.Errorf("Failed to parse the radio track %s", config.RadioSeedTrack)
+		}
+
+		return fulltrack.Get(client, id)
+	}
+
+	status, err := client.PlayerCurrentlyPlaying()
+	if err != nil {
+		return spotify.FullTrack{}, fmt.Errorf("Failed to get the currently playing track: %v", err)
+	}
+
+	// A paused track is as good a seed as a playing one
+	if status.Item == nil {
+		return spotify.FullTrack{}, fmt.Errorf("Found no track to seed the radio with, nothing is playing")
+	}
+
+	// Local files and episodes lack a Spotify track ID to recommend from
+	if status.Item.ID == "" {
+		return spotify.FullTrack{}, fmt.Errorf("Failed to seed the radio with %s, it's not a Spotify track", status.Item.Name)
+	}
+
+	logrus.Infof(
+		"Seeding the radio with the currently playing %s by %s",
+		status.Item.Name,
+		utils.JoinArtists(status.Item.Artists, ", "),
+	)
+
+	return fulltrack.Get(client, status.Item.ID)
+}
+
+func getRadioSuggestions(
+	client spotify.Client,
+	state State,
+	seedTrack spotify.FullTrack,
+) ([]suggestion.Suggestion, error) {
+	filters, history, err := getFilters(client)
+	if err != nil {
+		return []suggestion.Suggestion{}, err
+	}
+
+	// A radio plays whatever fits the seed, singles and earlier suggestions included
+	filters = filters.Without(candidatefilter.AlbumSizeFilterName, candidatefilter.HistoryFilterName)
+
+	// Library tracks and the artist limit drop plenty, so ask for more than needed
+	recommendedTracks, err := spotifyrecommendation.RecommendFromTrack(client, seedTrack, config.RadioSize*3)
+	if err != nil {
+		return []suggestion.Suggestion{}, err
+	}
+
+	scorer, err := getScorer(client, state.Library)
+	if err != nil {
+		return []suggestion.Suggestion{}, err
+	}
+
+	suggestions, err := suggestion.GetSuggestionsFromTracks(
+		client,
+		recommendedTracks,
+		state.Library,
+		scorer,
+		filters,
+		history,
+	)
+	if err != nil {
+		return suggestions, err
+	}
+
+	filters.LogSummary()
+
+	return suggestion.LimitPerArtist(suggestions, config.RadioMaxTracksPerArtist, config.RadioSize), nil
+}
+
+func getFilters(client spotify.Client) (candidatefilter.Pipeline, suggestionlog.History, error) {
+	lists, err := filterlist.Read()
+	if err != nil {
+		return candidatefilter.Pipeline{}, suggestionlog.History{}, err
+	}
+
+	history, err := getSuggestionHistory()
+	if err != nil {
+		return candidatefilter.Pipeline{}, history, err
+	}
+
+	return candidatefilter.CreateDefaultPipeline(client, lists, history), history, nil
+}
+
+func getSuggestionHistory() (suggestionlog.History, error) {
+	suggestionLog, err := suggestionlog.Read()
+	if err != nil {
+		return suggestionlog.History{}, err
+	}
+
+	since := time.Now().AddDate(0, 0, -config.HistoryWindowDays)
+
+	return suggestionLog.GetHistory(since), nil
+}
+
+func getScorer(client spotify.Client, library libraryindex.Index) (scoring.Scorer, error) {
+	profile, err := scoring.ReadProfile(config.ScoringProfilesFilename, config.ScoringProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Scoring suggestions using the %s profile", config.ScoringProfile)
+
+	return scoring.CreateScorer(client, library, profile)
+}
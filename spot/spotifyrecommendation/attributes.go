@@ -0,0 +1,140 @@
+package spotifyrecommendation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+// applyAttributeOverrides sets attributes given as <min|max|target>_<name>=<value>,
+// e.g. target_energy=0.8, replacing the ones derived from the seed tracks.
+func applyAttributeOverrides(attributes *spotify.TrackAttributes, overrides []string) error {
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Failed to parse the track attribute %s, expected <name>=<value>", override)
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return fmt.Errorf("Failed to parse the value of the track attribute %s: %v", override, err)
+		}
+
+		if err := applyAttribute(attributes, strings.ToLower(strings.TrimSpace(parts[0])), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getOverriddenAttributes returns the names of the features overridden in any
+// way, e.g. energy for min_energy=0.5, as the derived range may conflict with them.
+func getOverriddenAttributes(overrides []string) map[string]bool {
+	isOverridden := map[string]bool{}
+
+	for _, override := range overrides {
+		name := strings.ToLower(strings.TrimSpace(strings.SplitN(override, "=", 2)[0]))
+
+		if parts := strings.SplitN(name, "_", 2); len(parts) == 2 {
+			isOverridden[parts[1]] = true
+		}
+	}
+
+	return isOverridden
+}
+
+func applyAttribute(attributes *spotify.TrackAttributes, name string, value float64) error {
+	switch name {
+	case "min_acousticness":
+		attributes.MinAcousticness(value)
+	case "max_acousticness":
+		attributes.MaxAcousticness(value)
+	case "target_acousticness":
+		attributes.TargetAcousticness(value)
+	case "min_danceability":
+		attributes.MinDanceability(value)
+	case "max_danceability":
+		attributes.MaxDanceability(value)
+	case "target_danceability":
+		attributes.TargetDanceability(value)
+	case "min_energy":
+		attributes.MinEnergy(value)
+	case "max_energy":
+		attributes.MaxEnergy(value)
+	case "target_energy":
+		attributes.TargetEnergy(value)
+	case "min_instrumentalness":
+		attributes.MinInstrumentalness(value)
+	case "max_instrumentalness":
+		attributes.MaxInstrumentalness(value)
+	case "target_instrumentalness":
+		attributes.TargetInstrumentalness(value)
+	case "min_liveness":
+		attributes.MinLiveness(value)
+	case "max_liveness":
+		attributes.MaxLiveness(value)
+	case "target_liveness":
+		attributes.TargetLiveness(value)
+	case "min_loudness":
+		attributes.MinLoudness(value)
+	case "max_loudness":
+		attributes.MaxLoudness(value)
+	case "target_loudness":
+		attributes.TargetLoudness(value)
+	case "min_speechiness":
+		attributes.MinSpeechiness(value)
+	case "max_speechiness":
+		attributes.MaxSpeechiness(value)
+	case "target_speechiness":
+		attributes.TargetSpeechiness(value)
+	case "min_tempo":
+		attributes.MinTempo(value)
+	case "max_tempo":
+		attributes.MaxTempo(value)
+	case "target_tempo":
+		attributes.TargetTempo(value)
+	case "min_valence":
+		attributes.MinValence(value)
+	case "max_valence":
+		attributes.MaxValence(value)
+	case "target_valence":
+		attributes.TargetValence(value)
+	case "min_duration_ms":
+		attributes.MinDuration(int(value))
+	case "max_duration_ms":
+		attributes.MaxDuration(int(value))
+	case "target_duration_ms":
+		attributes.TargetDuration(int(value))
+	case "min_key":
+		attributes.MinKey(int(value))
+	case "max_key":
+		attributes.MaxKey(int(value))
+	case "target_key":
+		attributes.TargetKey(int(value))
+	case "min_mode":
+		attributes.MinMode(int(value))
+	case "max_mode":
+		attributes.MaxMode(int(value))
+	case "target_mode":
+		attributes.TargetMode(int(value))
+	case "min_popularity":
+		attributes.MinPopularity(int(value))
+	case "max_popularity":
+		attributes.MaxPopularity(int(value))
+	case "target_popularity":
+		attributes.TargetPopularity(int(value))
+	case "min_time_signature":
+		attributes.MinTimeSignature(int(value))
+	case "max_time_signature":
+		attributes.MaxTimeSignature(int(value))
+	case "target_time_signature":
+		attributes.TargetTimeSignature(int(value))
+	default:
+		return fmt.Errorf("Unknown track attribute %s", name)
+	}
+
+	return nil
+}
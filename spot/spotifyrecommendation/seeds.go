@@ -0,0 +1,248 @@
+package spotifyrecommendation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/listening"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+const maxSeedCount = 5
+
+type Rotation struct {
+	LastUsedAt map[string]time.Time
+}
+
+type seedPool struct {
+	artists []spotify.SimpleArtist
+	tracks  []spotify.FullTrack
+}
+
+type seedSet struct {
+	seeds       spotify.Seeds
+	description []string
+}
+
+func ReadRotation() (Rotation, error) {
+	rotation := Rotation{LastUsedAt: map[string]time.Time{}}
+
+	if err := cache.ReadCache(config.SeedRotationFilename, &rotation); err != nil {
+		return rotation, err
+	}
+
+	if rotation.LastUsedAt == nil {
+		rotation.LastUsedAt = map[string]time.Time{}
+	}
+
+	return rotation, nil
+}
+
+func WriteRotation(rotation Rotation) error {
+	return cache.WriteCache(config.SeedRotationFilename, rotation)
+}
+
+// getSeedPool returns the artists and tracks to draw seeds from, taken from the
+// seed playlist when one is configured and from the listening history otherwise.
+func getSeedPool(client spotify.Client, library libraryindex.Index) (seedPool, error) {
+	pool := seedPool{}
+	var err error
+
+	if config.SeedPlaylistName == "" {
+		pool.artists, err = listening.GetTopArtists(client, config.SeedTimeRange, config.SeedPoolSize)
+		if err != nil {
+			return pool, err
+		}
+
+		pool.tracks, err = listening.GetTopTracks(client, config.SeedTimeRange, config.SeedPoolSize)
+
+		return pool, err
+	}
+
+	for _, list := range library.Playlists {
+		if list.Name != config.SeedPlaylistName {
+			continue
+		}
+
+		pool.tracks = list.Tracks
+		pool.artists = getArtistsByTrackCount(list.Tracks)
+
+		return pool, nil
+	}
+
+	return pool, fmt.Errorf("Failed to find the seed playlist %s among the library playlists", config.SeedPlaylistName)
+}
+
+// createSeedSets combines the explicitly configured seeds with artists and
+// tracks from the pool, picking the ones used least recently first.
+func createSeedSets(client spotify.Client, pool seedPool, rotation Rotation) ([]seedSet, error) {
+	sets := []seedSet{}
+	isListed := map[string]bool{}
+
+	explicit, err := getExplicitSeeds(client)
+	if err != nil {
+		return sets, err
+	}
+
+	artists := append([]spotify.SimpleArtist{}, pool.artists...)
+	sort.SliceStable(artists, func(i, j int) bool {
+		return rotation.LastUsedAt[getSeedKey("artist", artists[i].ID)].Before(
+			rotation.LastUsedAt[getSeedKey("artist", artists[j].ID)],
+		)
+	})
+
+	tracks := fulltrack.GetUnique(pool.tracks)
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return rotation.LastUsedAt[getSeedKey("track", tracks[i].ID)].Before(
+			rotation.LastUsedAt[getSeedKey("track", tracks[j].ID)],
+		)
+	})
+
+	for index := 0; index < config.SeedSetCount; index++ {
+		set := seedSet{
+			seeds: spotify.Seeds{
+				Artists: append([]spotify.ID{}, explicit.seeds.Artists...),
+				Tracks:  append([]spotify.ID{}, explicit.seeds.Tracks...),
+				Genres:  append([]string{}, explicit.seeds.Genres...),
+			},
+			description: append([]string{}, explicit.description...),
+		}
+
+		for offset := 0; offset < config.SeedArtistsPerSet && len(artists) > 0; offset++ {
+			if getSeedCount(set.seeds) >= maxSeedCount {
+				break
+			}
+
+			artist := artists[(index*config.SeedArtistsPerSet+offset)%len(artists)]
+			set.seeds.Artists = append(set.seeds.Artists, artist.ID)
+			set.description = append(set.description, "artist "+artist.Name)
+		}
+
+		for offset := 0; offset < config.SeedTracksPerSet && len(tracks) > 0; offset++ {
+			if getSeedCount(set.seeds) >= maxSeedCount {
+				break
+			}
+
+			track := tracks[(index*config.SeedTracksPerSet+offset)%len(tracks)]
+			set.seeds.Tracks = append(set.seeds.Tracks, track.ID)
+			set.description = append(set.description, "track "+track.Name)
+		}
+
+		key := strings.Join(set.description, ",")
+		if getSeedCount(set.seeds) == 0 || isListed[key] {
+			continue
+		}
+
+		isListed[key] = true
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
+
+func getExplicitSeeds(client spotify.Client) (seedSet, error) {
+	set := seedSet{seeds: spotify.Seeds{}, description: []string{}}
+	artistIDs := []spotify.ID{}
+	trackIDs := []spotify.ID{}
+
+	for _, value := range config.SeedArtists {
+		artistIDs = append(artistIDs, utils.ParseSpotifyID(value))
+	}
+
+	for _, value := range config.SeedTracks {
+		trackIDs = append(trackIDs, utils.ParseSpotifyID(value))
+	}
+
+	if len(artistIDs)+len(trackIDs)+len(config.SeedGenres) > maxSeedCount {
+		return set, fmt.Errorf("Received more than %d explicit seeds", maxSeedCount)
+	}
+
+	if len(artistIDs) > 0 {
+		artists, err := client.GetArtists(artistIDs...)
+		if err != nil {
+			return set, fmt.Errorf("Failed to get the seed artists: %v", err)
+		}
+
+		// Unknown IDs are returned as nil, in the position they were requested in
+		for index, artist := range artists {
+			if artist == nil {
+				return set, fmt.Errorf("Failed to find the seed artist %s", artistIDs[index])
+			}
+
+			set.seeds.Artists = append(set.seeds.Artists, artist.ID)
+			set.description = append(set.description, "artist "+artist.Name)
+		}
+	}
+
+	if len(trackIDs) > 0 {
+		tracks, err := client.GetTracks(trackIDs...)
+		if err != nil {
+			return set, fmt.Errorf("Failed to get the seed tracks: %v", err)
+		}
+
+		for index, track := range tracks {
+			if track == nil {
+				return set, fmt.Errorf("Failed to find the seed track %s", trackIDs[index])
+			}
+
+			set.seeds.Tracks = append(set.seeds.Tracks, track.ID)
+			set.description = append(set.description, "track "+track.Name)
+		}
+	}
+
+	for _, genre := range config.SeedGenres {
+		set.seeds.Genres = append(set.seeds.Genres, genre)
+		set.description = append(set.description, "genre "+genre)
+	}
+
+	return set, nil
+}
+
+func markUsed(rotation Rotation, sets []seedSet, usedAt time.Time) {
+	for _, set := range sets {
+		for _, id := range set.seeds.Artists {
+			rotation.LastUsedAt[getSeedKey("artist", id)] = usedAt
+		}
+
+		for _, id := range set.seeds.Tracks {
+			rotation.LastUsedAt[getSeedKey("track", id)] = usedAt
+		}
+	}
+}
+
+func getArtistsByTrackCount(tracks []spotify.FullTrack) []spotify.SimpleArtist {
+	artists := []spotify.SimpleArtist{}
+	counts := map[spotify.ID]int{}
+
+	for _, track := range tracks {
+		for _, artist := range track.Artists {
+			if counts[artist.ID] == 0 {
+				artists = append(artists, artist)
+			}
+
+			counts[artist.ID]++
+		}
+	}
+
+	sort.SliceStable(artists, func(i, j int) bool {
+		return counts[artists[i].ID] > counts[artists[j].ID]
+	})
+
+	return artists
+}
+
+func getSeedCount(seeds spotify.Seeds) int {
+	return len(seeds.Artists) + len(seeds.Tracks) + len(seeds.Genres)
+}
+
+func getSeedKey(kind string, id spotify.ID) string {
+	return fmt.Sprintf("%s:%s", kind, id)
+}
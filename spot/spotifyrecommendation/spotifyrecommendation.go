@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/zmb3/spotify"
 
+	"github.com/kristofferostlund/spot/spot/audiofeature"
 	"github.com/kristofferostlund/spot/spot/config"
 	"github.com/kristofferostlund/spot/spot/fullalbum"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/spotifytrack"
 	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
 	"github.com/kristofferostlund/spot/spot/utils"
 )
@@ -17,136 +21,256 @@ import (
 type RecommendationParameters struct {
 	Seeds           spotify.Seeds
 	TrackAttributes *spotify.TrackAttributes
-	FromYear        int
 	MinTrackCount   int
+	MaxRequests     int
 }
 
-func Recommend(client spotify.Client) ([]spotify.FullTrack, error) {
+func Recommend(
+	client spotify.Client,
+	library libraryindex.Index,
+) ([]spotify.FullTrack, error) {
 	tracks := []spotify.FullTrack{}
-	pageLimit := 5
+	seenTracks := spotifytrack.FullTrackMap{}
 
-	userTopArtists, err := client.CurrentUsersTopArtistsOpt(&spotify.Options{Limit: &pageLimit})
+	pool, err := getSeedPool(client, library)
 	if err != nil {
-		return tracks, fmt.Errorf("Failed to get user's top artists: %v", err)
+		return tracks, err
 	}
 
-	userTopTracks, err := client.CurrentUsersTopTracks()
+	trackAttributes, err := getTrackAttributes(client, pool.tracks, config.TrackAttributeOverrides)
 	if err != nil {
-		return tracks, fmt.Errorf("Failed to get user's top tracks: %v", err)
+		return tracks, err
 	}
 
-	trackAttributes, err := getTrackAttributes(client, userTopTracks.Tracks)
+	rotation, err := ReadRotation()
 	if err != nil {
 		return tracks, err
 	}
 
-	for _, artist := range userTopArtists.Artists {
-		logrus.Infof("Fetching recommendations seeded by artist %s", artist.Name)
+	seedSets, err := createSeedSets(client, pool, rotation)
+	if err != nil {
+		return tracks, err
+	}
+
+	for _, set := range seedSets {
+		description := strings.Join(set.description, ", ")
+
+		logrus.Infof("Fetching recommendations seeded by %s", description)
 
 		params := RecommendationParameters{
-			FromYear:      2016,
-			MinTrackCount: 100,
-			Seeds: spotify.Seeds{
-				Artists: []spotify.ID{artist.ID},
-			},
+			MinTrackCount:   100,
+			MaxRequests:     config.RecommendationRequestBudget,
+			Seeds:           set.seeds,
 			TrackAttributes: trackAttributes,
 		}
 
-		pageTracks, err := getRecommendedTracks(client, params)
+		pageTracks, err := getRecommendedTracks(client, params, seenTracks)
 		if err != nil {
 			return tracks, err
 		}
 
-		logrus.Infof("Fetched %d recommendations seeded by artist %s", len(pageTracks), artist.Name)
+		logrus.Infof("Fetched %d recommendations seeded by %s", len(pageTracks), description)
 
 		tracks = append(tracks, pageTracks...)
 	}
 
+	markUsed(rotation, seedSets, time.Now())
+
+	if err := WriteRotation(rotation); err != nil {
+		return tracks, err
+	}
+
 	return tracks, nil
 }
 
-func getRecommendedTracks(client spotify.Client, params RecommendationParameters) ([]spotify.FullTrack, error) {
+// RecommendFromTrack fetches recommendations seeded by a single track, only
+// applying the track attributes given as overrides.
+func RecommendFromTrack(
+	client spotify.Client,
+	track spotify.FullTrack,
+	minTrackCount int,
+) ([]spotify.FullTrack, error) {
+	attributes := spotify.NewTrackAttributes()
+
+	if err := applyAttributeOverrides(attributes, config.TrackAttributeOverrides); err != nil {
+		return []spotify.FullTrack{}, err
+	}
+
+	// Excluding the seed keeps it from being recommended back
+	seenTracks := spotifytrack.FullTrackMap{fulltrack.GetKey(track): track}
+
+	params := RecommendationParameters{
+		MinTrackCount:   minTrackCount,
+		MaxRequests:     config.RecommendationRequestBudget,
+		Seeds:           spotify.Seeds{Tracks: []spotify.ID{track.ID}},
+		TrackAttributes: attributes,
+	}
+
+	return getRecommendedTracks(client, params, seenTracks)
+}
+
+func getRecommendedTracks(
+	client spotify.Client,
+	params RecommendationParameters,
+	seenTracks spotifytrack.FullTrackMap,
+) ([]spotify.FullTrack, error) {
 	pageLimit := 100
-	trackCount := 0
-	totalCount := 0
+	requestCount := 0
+	seenIDs := map[spotify.ID]bool{}
 	tracks := []spotify.FullTrack{}
 
 	options := spotify.Options{
 		Limit:   &pageLimit,
-		Offset:  &totalCount,
 		Country: &config.Country,
 	}
 
-	page, err := client.GetRecommendations(params.Seeds, params.TrackAttributes, &options)
-	if err != nil {
-		return tracks, fmt.Errorf("Failed to get recommendations: %v", err)
-	}
+	for len(tracks) < params.MinTrackCount && requestCount < params.MaxRequests {
+		requestCount++
 
-	totalCount += len(page.Tracks)
+		page, err := client.GetRecommendations(params.Seeds, params.TrackAttributes, &options)
+		if err != nil {
+			return tracks, fmt.Errorf("Failed to get recommendations: %v", err)
+		}
 
-	fullTracks, err := fulltrack.GetMany(client, utils.GetSpotifyIDs(page.Tracks))
-	if err != nil {
-		return tracks, err
-	}
+		// The recommendations endpoint doesn't support paging, so each request is a
+		// re-query where only the tracks we haven't seen before are of any interest.
+		newIDs := []spotify.ID{}
+		for _, id := range utils.GetSpotifyIDs(page.Tracks) {
+			if !seenIDs[id] {
+				seenIDs[id] = true
+				newIDs = append(newIDs, id)
+			}
+		}
 
-	for _, track := range fullTracks {
-		album, err := fullalbum.Get(client, track.Album.ID)
+		if len(newIDs) == 0 {
+			logrus.Debugf("No new recommendations on request %d/%d", requestCount, params.MaxRequests)
+
+			break
+		}
+
+		fullTracks, err := fulltrack.GetMany(client, newIDs)
+		if err != nil {
+			return tracks, err
+		}
+
+		// Fetching the albums in batches caches them for creating the suggestions,
+		// which is where the candidates are filtered
+		albums, err := getAlbumMap(client, fullTracks)
 		if err != nil {
 			return tracks, err
 		}
 
-		if album.ReleaseDateTime().Year() >= params.FromYear {
-			trackCount++
+		for _, track := range fullTracks {
+			if fulltrack.InMap(seenTracks, track) {
+				continue
+			}
+
+			if _, exists := albums[track.Album.ID]; !exists {
+				continue
+			}
+
+			seenTracks[fulltrack.GetKey(track)] = track
 			tracks = append(tracks, track)
 		}
 	}
 
+	if len(tracks) < params.MinTrackCount {
+		logrus.Debugf(
+			"Collected %d/%d recommendations within the budget of %d request(s)",
+			len(tracks),
+			params.MinTrackCount,
+			params.MaxRequests,
+		)
+	}
+
 	return tracks, nil
 }
 
-func getTrackAttributes(client spotify.Client, tracks []spotify.FullTrack) (*spotify.TrackAttributes, error) {
+func getAlbumMap(client spotify.Client, tracks []spotify.FullTrack) (map[spotify.ID]spotify.FullAlbum, error) {
+	albumMap := map[spotify.ID]spotify.FullAlbum{}
+	albumIDs := []spotify.ID{}
+	isListed := map[spotify.ID]bool{}
+
+	for _, track := range tracks {
+		if !isListed[track.Album.ID] {
+			isListed[track.Album.ID] = true
+			albumIDs = append(albumIDs, track.Album.ID)
+		}
+	}
+
+	albums, err := fullalbum.GetMany(client, albumIDs)
+	if err != nil {
+		return albumMap, err
+	}
+
+	for _, album := range albums {
+		albumMap[album.ID] = album
+	}
+
+	return albumMap, nil
+}
+
+func getTrackAttributes(
+	client spotify.Client,
+	tracks []spotify.FullTrack,
+	overrides []string,
+) (*spotify.TrackAttributes, error) {
 	var attributes *spotify.TrackAttributes
 
-	features, err := client.GetAudioFeatures(utils.GetSpotifyIDs(tracks)...)
+	featureMap, err := audiofeature.GetMany(client, utils.GetSpotifyIDs(tracks))
 	if err != nil {
-		return attributes, fmt.Errorf(
-			"Failed to get audio features of %d track(s): %v",
-			len(tracks),
-			err,
-		)
+		return attributes, err
+	}
+
+	features := []spotify.AudioFeatures{}
+	for _, feature := range featureMap {
+		features = append(features, feature)
 	}
 
-	acousticness := []float64{}
-	instrumentalness := []float64{}
-	liveness := []float64{}
-	energy := []float64{}
-	valence := []float64{}
-
-	for _, feature := range features {
-		acousticness = append(acousticness, float64(feature.Acousticness))
-		instrumentalness = append(instrumentalness, float64(feature.Instrumentalness))
-		liveness = append(liveness, float64(feature.Liveness))
-		energy = append(energy, float64(feature.Energy))
-		valence = append(valence, float64(feature.Valence))
-	}
-
-	averageAcousticness := utils.AverageFloat(acousticness)
-	averageInstrumentalness := utils.AverageFloat(instrumentalness)
-	averageLiveness := utils.AverageFloat(liveness)
-	averageEnergy := utils.AverageFloat(energy)
-	averageValence := utils.AverageFloat(valence)
-
-	attributes = spotify.NewTrackAttributes().
-		MaxAcousticness(asAttribute("max", averageAcousticness)).
-		MinAcousticness(asAttribute("min", averageAcousticness)).
-		MaxEnergy(asAttribute("max", averageEnergy)).
-		MinEnergy(asAttribute("min", averageEnergy)).
-		MaxInstrumentalness(asAttribute("max", averageInstrumentalness)).
-		MinInstrumentalness(asAttribute("min", averageInstrumentalness)).
-		MaxLiveness(asAttribute("max", averageLiveness)).
-		MinLiveness(asAttribute("min", averageLiveness)).
-		MaxValence(asAttribute("max", averageValence)).
-		MinValence(asAttribute("min", averageValence))
+	profile := audiofeature.CreateProfile(features)
+
+	averageAcousticness := profile[audiofeature.Acousticness].Mean
+	averageInstrumentalness := profile[audiofeature.Instrumentalness].Mean
+	averageLiveness := profile[audiofeature.Liveness].Mean
+	averageEnergy := profile[audiofeature.Energy].Mean
+	averageValence := profile[audiofeature.Valence].Mean
+
+	attributes = spotify.NewTrackAttributes()
+	isOverridden := getOverriddenAttributes(overrides)
+
+	if !isOverridden[audiofeature.Acousticness] {
+		attributes.
+			MaxAcousticness(asAttribute("max", averageAcousticness)).
+			MinAcousticness(asAttribute("min", averageAcousticness))
+	}
+
+	if !isOverridden[audiofeature.Energy] {
+		attributes.
+			MaxEnergy(asAttribute("max", averageEnergy)).
+			MinEnergy(asAttribute("min", averageEnergy))
+	}
+
+	if !isOverridden[audiofeature.Instrumentalness] {
+		attributes.
+			MaxInstrumentalness(asAttribute("max", averageInstrumentalness)).
+			MinInstrumentalness(asAttribute("min", averageInstrumentalness))
+	}
+
+	if !isOverridden[audiofeature.Liveness] {
+		attributes.
+			MaxLiveness(asAttribute("max", averageLiveness)).
+			MinLiveness(asAttribute("min", averageLiveness))
+	}
+
+	if !isOverridden[audiofeature.Valence] {
+		attributes.
+			MaxValence(asAttribute("max", averageValence)).
+			MinValence(asAttribute("min", averageValence))
+	}
+
+	if err := applyAttributeOverrides(attributes, overrides); err != nil {
+		return attributes, err
+	}
 
 	return attributes, nil
 }
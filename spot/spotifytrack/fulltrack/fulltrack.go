@@ -3,8 +3,11 @@ package fulltrack
 import (
 	"fmt"
 
-	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kristofferostlund/spot/spot/artistidentity"
 	"github.com/kristofferostlund/spot/spot/spotifytrack"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/trackidentity"
 	"github.com/kristofferostlund/spot/spot/utils"
 	"github.com/zmb3/spotify"
 )
@@ -34,7 +37,14 @@ func GetMany(client spotify.Client, ids []spotify.ID) ([]spotify.FullTrack, erro
 			return tracks, fmt.Errorf("Failed to get many tracks: %v", err)
 		}
 
-		for _, track := range pointerTracks {
+		// Tracks which are no longer available are returned as nil
+		for index, track := range pointerTracks {
+			if track == nil {
+				logrus.Warnf("Failed to find track %s, skipping it", chunkIDs[index])
+
+				continue
+			}
+
 			tracks = append(tracks, *track)
 		}
 	}
@@ -44,9 +54,13 @@ func GetMany(client spotify.Client, ids []spotify.ID) ([]spotify.FullTrack, erro
 
 func GetUnique(tracks []spotify.FullTrack) []spotify.FullTrack {
 	uniqueTracks := []spotify.FullTrack{}
+	trackCache := spotifytrack.FullTrackMap{}
 
-	for _, track := range CreateMap(tracks) {
-		uniqueTracks = append(uniqueTracks, track)
+	for _, track := range tracks {
+		if !InMap(trackCache, track) {
+			addToMap(trackCache, track)
+			uniqueTracks = append(uniqueTracks, track)
+		}
 	}
 
 	return uniqueTracks
@@ -69,40 +83,34 @@ func CreateMap(tracks []spotify.FullTrack) spotifytrack.FullTrackMap {
 	trackCache := spotifytrack.FullTrackMap{}
 
 	for _, track := range tracks {
-		trackCache[getMapKey(track)] = track
+		addToMap(trackCache, track)
 	}
 
 	return trackCache
 }
 
 func InMap(trackCache spotifytrack.FullTrackMap, track spotify.FullTrack) bool {
-	_, exists := trackCache[getMapKey(track)]
+	if _, exists := trackCache[trackidentity.FromFullTrack(track)]; exists {
+		return true
+	}
 
-	return exists
+	// Names are only compared when either of the tracks lacks an ISRC, otherwise
+	// different recordings with identical names would be considered the same.
+	found, exists := trackCache[trackidentity.GetNameKey(track.Name, track.Artists)]
+
+	return exists && (!trackidentity.HasISRC(found) || !trackidentity.HasISRC(track))
 }
 
 func InSlice(tracks []spotify.FullTrack, track spotify.FullTrack) bool {
-	trackCacheKey := getMapKey(track)
-
-	for _, sliceTrack := range tracks {
-		if trackCacheKey == getMapKey(sliceTrack) {
-			return true
-		}
-	}
-
-	return false
+	return InMap(CreateMap(tracks), track)
 }
 
 func GroupByArtists(tracks []spotify.FullTrack) spotifytrack.ArtistFullTrackMap {
 	grouped := spotifytrack.ArtistFullTrackMap{}
 
 	for _, track := range tracks {
-		artistsKey := getArtistGroupKey(track.Artists)
-
-		if _, exists := grouped[artistsKey]; exists {
-			grouped[artistsKey] = append(grouped[artistsKey], track)
-		} else {
-			grouped[artistsKey] = []spotify.FullTrack{track}
+		for _, artistKey := range artistidentity.GetKeys(track.Artists) {
+			grouped[artistKey] = append(grouped[artistKey], track)
 		}
 	}
 
@@ -110,23 +118,21 @@ func GroupByArtists(tracks []spotify.FullTrack) spotifytrack.ArtistFullTrackMap
 }
 
 func GetTrackCountByArtists(tracksByArtist spotifytrack.ArtistFullTrackMap, artists []spotify.SimpleArtist) int {
-	artistKey := getArtistGroupKey(artists)
+	counts := []int{}
 
-	if tracks, exists := tracksByArtist[artistKey]; exists {
-		return len(tracks)
+	for _, artistKey := range artistidentity.GetKeys(artists) {
+		counts = append(counts, len(tracksByArtist[artistKey]))
 	}
 
-	return 0
+	return artistidentity.CombineAffinity(counts)
 }
 
-func getMapKey(track spotify.FullTrack) string {
-	return fmt.Sprintf(
-		"%s:%s",
-		track.Name,
-		utils.JoinArtists(track.Artists, config.ArtistJoinCharacter),
-	)
+func GetKey(track spotify.FullTrack) string {
+	return trackidentity.FromFullTrack(track)
 }
 
-func getArtistGroupKey(artists []spotify.SimpleArtist) string {
-	return utils.JoinArtists(artists, config.ArtistJoinCharacter)
+func addToMap(trackCache spotifytrack.FullTrackMap, track spotify.FullTrack) {
+	for _, key := range trackidentity.GetKeys(track) {
+		trackCache[key] = track
+	}
 }
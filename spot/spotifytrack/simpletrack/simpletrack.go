@@ -1,19 +1,21 @@
 package simpletrack
 
 import (
-	"fmt"
-
-	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/artistidentity"
 	"github.com/kristofferostlund/spot/spot/spotifytrack"
-	"github.com/kristofferostlund/spot/spot/utils"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/trackidentity"
 	"github.com/zmb3/spotify"
 )
 
 func GetUnique(tracks []spotify.SimpleTrack) []spotify.SimpleTrack {
 	uniqueTracks := []spotify.SimpleTrack{}
+	trackCache := spotifytrack.SimpleTrackMap{}
 
-	for _, track := range CreateMap(tracks) {
-		uniqueTracks = append(uniqueTracks, track)
+	for _, track := range tracks {
+		if !InMap(trackCache, track) {
+			trackCache[trackidentity.FromSimpleTrack(track)] = track
+			uniqueTracks = append(uniqueTracks, track)
+		}
 	}
 
 	return uniqueTracks
@@ -36,23 +38,23 @@ func CreateMap(tracks []spotify.SimpleTrack) spotifytrack.SimpleTrackMap {
 	trackCache := spotifytrack.SimpleTrackMap{}
 
 	for _, track := range tracks {
-		trackCache[getMapKey(track)] = track
+		trackCache[trackidentity.FromSimpleTrack(track)] = track
 	}
 
 	return trackCache
 }
 
 func InMap(trackCache spotifytrack.SimpleTrackMap, track spotify.SimpleTrack) bool {
-	_, exists := trackCache[getMapKey(track)]
+	_, exists := trackCache[trackidentity.FromSimpleTrack(track)]
 
 	return exists
 }
 
 func InSlice(tracks []spotify.SimpleTrack, track spotify.SimpleTrack) bool {
-	trackCacheKey := getMapKey(track)
+	trackCacheKey := trackidentity.FromSimpleTrack(track)
 
 	for _, sliceTrack := range tracks {
-		if trackCacheKey == getMapKey(sliceTrack) {
+		if trackCacheKey == trackidentity.FromSimpleTrack(sliceTrack) {
 			return true
 		}
 	}
@@ -64,12 +66,8 @@ func GroupByArtists(tracks []spotify.SimpleTrack) spotifytrack.ArtistSimpleTrack
 	grouped := spotifytrack.ArtistSimpleTrackMap{}
 
 	for _, track := range tracks {
-		for _, artist := range track.Artists {
-			if _, exists := grouped[artist.Name]; exists {
-				grouped[artist.Name] = append(grouped[artist.Name], track)
-			} else {
-				grouped[artist.Name] = []spotify.SimpleTrack{track}
-			}
+		for _, artistKey := range artistidentity.GetKeys(track.Artists) {
+			grouped[artistKey] = append(grouped[artistKey], track)
 		}
 	}
 
@@ -77,23 +75,11 @@ func GroupByArtists(tracks []spotify.SimpleTrack) spotifytrack.ArtistSimpleTrack
 }
 
 func GetTrackCountByArtist(tracksByArtist spotifytrack.ArtistSimpleTrackMap, artists []spotify.SimpleArtist) int {
-	artistKey := getArtistGroupKey(artists)
+	counts := []int{}
 
-	if tracks, exists := tracksByArtist[artistKey]; exists {
-		return len(tracks)
-	} else {
-		return 0
+	for _, artistKey := range artistidentity.GetKeys(artists) {
+		counts = append(counts, len(tracksByArtist[artistKey]))
 	}
-}
-
-func getMapKey(track spotify.SimpleTrack) string {
-	return fmt.Sprintf(
-		"%s:%s",
-		track.Name,
-		utils.JoinArtists(track.Artists, config.ArtistJoinCharacter),
-	)
-}
 
-func getArtistGroupKey(artists []spotify.SimpleArtist) string {
-	return utils.JoinArtists(artists, config.ArtistJoinCharacter)
+	return artistidentity.CombineAffinity(counts)
 }
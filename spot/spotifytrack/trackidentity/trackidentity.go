@@ -0,0 +1,72 @@
+package trackidentity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/config"
+)
+
+const (
+	isrcKeyPrefix = "isrc"
+	nameKeyPrefix = "name"
+	isrcIDKey     = "isrc"
+)
+
+func FromFullTrack(track spotify.FullTrack) string {
+	if isrc, exists := GetISRC(track); exists {
+		return fmt.Sprintf("%s:%s", isrcKeyPrefix, isrc)
+	}
+
+	return GetNameKey(track.Name, track.Artists)
+}
+
+func FromSimpleTrack(track spotify.SimpleTrack) string {
+	return GetNameKey(track.Name, track.Artists)
+}
+
+func GetKeys(track spotify.FullTrack) []string {
+	nameKey := GetNameKey(track.Name, track.Artists)
+
+	if _, exists := GetISRC(track); exists {
+		return []string{FromFullTrack(track), nameKey}
+	}
+
+	return []string{nameKey}
+}
+
+func GetISRC(track spotify.FullTrack) (string, bool) {
+	isrc := strings.ToUpper(strings.TrimSpace(track.ExternalIDs[isrcIDKey]))
+
+	return isrc, isrc != ""
+}
+
+func HasISRC(track spotify.FullTrack) bool {
+	_, exists := GetISRC(track)
+
+	return exists
+}
+
+func GetNameKey(name string, artists []spotify.SimpleArtist) string {
+	artistNames := []string{}
+
+	for _, artist := range artists {
+		artistNames = append(artistNames, normalize(artist.Name))
+	}
+
+	sort.Strings(artistNames)
+
+	return fmt.Sprintf(
+		"%s:%s:%s",
+		nameKeyPrefix,
+		normalize(name),
+		strings.Join(artistNames, config.ArtistJoinCharacter),
+	)
+}
+
+func normalize(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), " ")
+}
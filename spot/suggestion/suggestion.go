@@ -1,6 +1,7 @@
 package suggestion
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -8,42 +9,82 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/zmb3/spotify"
 
+	"github.com/kristofferostlund/spot/spot/artistidentity"
+	"github.com/kristofferostlund/spot/spot/candidatefilter"
 	"github.com/kristofferostlund/spot/spot/config"
 	"github.com/kristofferostlund/spot/spot/fullalbum"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
 	"github.com/kristofferostlund/spot/spot/playlist"
-	"github.com/kristofferostlund/spot/spot/spotifytrack"
+	"github.com/kristofferostlund/spot/spot/scoring"
 	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/suggestionlog"
 	"github.com/kristofferostlund/spot/spot/utils"
 )
 
 type Suggestion struct {
-	Playlist  playlist.Playlist
-	Track     spotify.FullTrack
-	Album     spotify.FullAlbum
-	Relevance int
+	Playlist                playlist.Playlist
+	Sources                 []playlist.Playlist
+	Via                     []string
+	Track                   spotify.FullTrack
+	Album                   spotify.FullAlbum
+	Relevance               float64
+	Breakdown               []scoring.Contribution
+	PreviousSuggestionCount int
 }
 
-func (s *Suggestion) CalculateRelevance(tracksByArtist spotifytrack.ArtistFullTrackMap) {
-	s.Relevance = 0
+type exportedSuggestion struct {
+	Name      string                 `json:"name"`
+	Artists   []string               `json:"artists"`
+	Album     string                 `json:"album"`
+	Year      int                    `json:"year"`
+	Playlist  string                 `json:"playlist"`
+	Sources   []string               `json:"sources"`
+	Via       string                 `json:"via,omitempty"`
+	URI       spotify.URI            `json:"uri"`
+	Relevance float64                `json:"relevance"`
+	Breakdown []scoring.Contribution `json:"breakdown,omitempty"`
+
+	PreviousSuggestionCount int `json:"previousSuggestionCount"`
+}
 
-	modifiers := []int{
-		s.Album.ReleaseDateTime().Year() - 2000,
-		fulltrack.GetTrackCountByArtists(tracksByArtist, s.Track.Artists),
+func (s Suggestion) GetCandidate() scoring.Candidate {
+	return scoring.Candidate{
+		Track:                   s.Track,
+		Album:                   s.Album,
+		Playlist:                s.Playlist,
+		Sources:                 s.Sources,
+		PreviousSuggestionCount: s.PreviousSuggestionCount,
 	}
+}
 
-	for word, penalty := range config.WordPenaltyMap {
-		if strings.Contains(strings.ToLower(s.Track.Name), word) {
-			modifiers = append(modifiers, penalty)
-		}
+// GetOrigin describes where the suggestion came from, e.g. "via A → B" when
+// found by crawling related artists.
+func (s Suggestion) GetOrigin() string {
+	if len(s.Via) > 0 {
+		return "via " + strings.Join(s.Via, " → ")
 	}
 
-	if s.Playlist.Name == config.FavouredPlaylistName {
-		modifiers = append(modifiers, config.FavouredPlaylistAddedScore)
+	return strings.Join(s.GetSourceNames(), ", ")
+}
+
+func (s Suggestion) GetSourceNames() []string {
+	names := []string{}
+
+	for _, source := range s.Sources {
+		names = append(names, source.Name)
 	}
 
-	for _, score := range modifiers {
-		s.Relevance += score
+	return names
+}
+
+func (s Suggestion) hasSource(source playlist.Playlist) bool {
+	for _, existing := range s.Sources {
+		if existing.ID == source.ID {
+			return true
+		}
 	}
+
+	return false
 }
 
 func CreateSuggestion(
@@ -51,7 +92,11 @@ func CreateSuggestion(
 	originPlaylist playlist.Playlist,
 	track spotify.FullTrack,
 ) (Suggestion, error) {
-	suggestion := Suggestion{Playlist: originPlaylist}
+	suggestion := Suggestion{Playlist: originPlaylist, Sources: []playlist.Playlist{}}
+
+	if originPlaylist.ID != "" {
+		suggestion.Sources = append(suggestion.Sources, originPlaylist)
+	}
 
 	album, err := fullalbum.GetAlbumByTrack(client, track)
 	if err != nil {
@@ -73,7 +118,6 @@ func CreateSuggestion(
 
 	suggestion.Track = track
 	suggestion.Album = album
-	suggestion.CalculateRelevance(spotifytrack.ArtistFullTrackMap{})
 
 	return suggestion, nil
 }
@@ -81,35 +125,58 @@ func CreateSuggestion(
 func GetSuggestions(
 	client spotify.Client,
 	discoveryPlaylists []playlist.Playlist,
-	existingTracks []spotify.FullTrack,
+	library libraryindex.Index,
+	scorer scoring.Scorer,
+	filters candidatefilter.Pipeline,
+	history suggestionlog.History,
 ) ([]Suggestion, error) {
-	trackMap := fulltrack.CreateMap(existingTracks)
-	tracksByArtist := fulltrack.GroupByArtists(existingTracks)
-
 	suggestions := []Suggestion{}
 
 	logrus.Info("Generating suggestions")
 
+	candidateTracks := []spotify.FullTrack{}
+	for _, discoveryPlaylist := range discoveryPlaylists {
+		candidateTracks = append(candidateTracks, discoveryPlaylist.Tracks...)
+	}
+
+	if err := filters.Prepare(candidateTracks); err != nil {
+		return suggestions, err
+	}
+
+	seen := createSeenTracks()
+
 	for _, discoveryPlaylist := range discoveryPlaylists {
 		for _, track := range discoveryPlaylist.Tracks {
-			if !fulltrack.InMap(trackMap, track) {
+			if index, isSeen := seen.find(track); isSeen {
+				if index >= 0 && !suggestions[index].hasSource(discoveryPlaylist) {
+					suggestions[index].Sources = append(suggestions[index].Sources, discoveryPlaylist)
+				}
+
+				continue
+			}
+
+			seen.skip(track)
+
+			if !library.Contains(track) {
 				suggestion, err := CreateSuggestion(client, discoveryPlaylist, track)
 				if err != nil {
 					return suggestions, err
 				}
 
-				if suggestion.Album.Tracks.Total > config.MinimumAlbumTotalCount {
-					suggestion.CalculateRelevance(tracksByArtist)
+				suggestion.PreviousSuggestionCount = history.Get(suggestion.Track).Count
 
+				if filters.Keep(suggestion.Track, suggestion.Album) {
+					seen.add(track, len(suggestions))
 					suggestions = append(suggestions, suggestion)
 				}
 			}
 		}
 	}
 
-	sort.Slice(suggestions, func(i, j int) bool {
-		return suggestions[i].Relevance > suggestions[j].Relevance
-	})
+	suggestions, err := rank(suggestions, scorer)
+	if err != nil {
+		return suggestions, err
+	}
 
 	logrus.Infof("Successfully generated %d suggestions", len(suggestions))
 
@@ -119,33 +186,62 @@ func GetSuggestions(
 func GetSuggestionsFromTracks(
 	client spotify.Client,
 	baseTracks []spotify.FullTrack,
-	existingTracks []spotify.FullTrack,
+	library libraryindex.Index,
+	scorer scoring.Scorer,
+	filters candidatefilter.Pipeline,
+	history suggestionlog.History,
 ) ([]Suggestion, error) {
-	trackMap := fulltrack.CreateMap(existingTracks)
-	tracksByArtist := fulltrack.GroupByArtists(existingTracks)
+	return GetSuggestionsFromPaths(client, baseTracks, map[spotify.ID][]string{}, library, scorer, filters, history)
+}
 
+// GetSuggestionsFromPaths works like GetSuggestionsFromTracks, tagging each
+// suggestion with the path of artist names that led to its track.
+func GetSuggestionsFromPaths(
+	client spotify.Client,
+	baseTracks []spotify.FullTrack,
+	paths map[spotify.ID][]string,
+	library libraryindex.Index,
+	scorer scoring.Scorer,
+	filters candidatefilter.Pipeline,
+	history suggestionlog.History,
+) ([]Suggestion, error) {
 	suggestions := []Suggestion{}
 
 	logrus.Info("Generating suggestions")
 
+	if err := filters.Prepare(baseTracks); err != nil {
+		return suggestions, err
+	}
+
+	seen := createSeenTracks()
+
 	for _, track := range baseTracks {
-		if !fulltrack.InMap(trackMap, track) {
+		if _, isSeen := seen.find(track); isSeen {
+			continue
+		}
+
+		seen.skip(track)
+
+		if !library.Contains(track) {
 			suggestion, err := CreateSuggestion(client, playlist.Playlist{}, track)
 			if err != nil {
 				return suggestions, err
 			}
 
-			if suggestion.Album.Tracks.Total > config.MinimumAlbumTotalCount {
-				suggestion.CalculateRelevance(tracksByArtist)
+			suggestion.Via = paths[track.ID]
+
+			suggestion.PreviousSuggestionCount = history.Get(suggestion.Track).Count
 
+			if filters.Keep(suggestion.Track, suggestion.Album) {
 				suggestions = append(suggestions, suggestion)
 			}
 		}
 	}
 
-	sort.Slice(suggestions, func(i, j int) bool {
-		return suggestions[i].Relevance > suggestions[j].Relevance
-	})
+	suggestions, err := rank(suggestions, scorer)
+	if err != nil {
+		return suggestions, err
+	}
 
 	logrus.Infof("Successfully generated %d suggestions", len(suggestions))
 
@@ -166,21 +262,98 @@ func CreatePrintableTable(suggestions []Suggestion) string {
 
 	for index, s := range suggestions {
 		output += fmt.Sprintf(
-			"%-02d %s %s %s %s %-6d %-5d %36s\n",
+			"%-02d %s %s %s %s %-6d %-5.1f %36s",
 			index+1,
 			utils.FixedWidthString(s.Track.Name, 30),
-			utils.FixedWidthString(s.Playlist.Name, 30),
+			utils.FixedWidthString(s.GetOrigin(), 30),
 			utils.FixedWidthString(utils.JoinArtists(s.Track.Artists, ", "), 30),
 			utils.FixedWidthString(s.Album.Name, 30),
 			s.Album.ReleaseDateTime().Year(),
 			s.Relevance,
 			s.Track.URI,
 		)
+
+		if config.ShowHistory && s.PreviousSuggestionCount > 0 {
+			output += fmt.Sprintf(" previously suggested %d time(s)", s.PreviousSuggestionCount)
+		}
+
+		output += "\n"
+
+		if config.Explain {
+			output += createPrintableBreakdown(s.Breakdown)
+		}
 	}
 
 	return output
 }
 
+func CreateJSON(suggestions []Suggestion) (string, error) {
+	exported := []exportedSuggestion{}
+
+	for _, s := range suggestions {
+		item := exportedSuggestion{
+			Name:      s.Track.Name,
+			Artists:   utils.GetSpotifyNames(s.Track.Artists),
+			Album:     s.Album.Name,
+			Year:      s.Album.ReleaseDateTime().Year(),
+			Playlist:  s.Playlist.Name,
+			Sources:   s.GetSourceNames(),
+			Via:       getVia(s),
+			URI:       s.Track.URI,
+			Relevance: s.Relevance,
+
+			PreviousSuggestionCount: s.PreviousSuggestionCount,
+		}
+
+		if config.Explain {
+			item.Breakdown = s.Breakdown
+		}
+
+		exported = append(exported, item)
+	}
+
+	jsonBytes, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal %d suggestion(s): %v", len(suggestions), err)
+	}
+
+	return string(jsonBytes), nil
+}
+
+// LimitPerArtist keeps the suggestions in order, skipping the ones crediting an
+// artist already credited maxPerArtist times, until size suggestions are kept.
+func LimitPerArtist(suggestions []Suggestion, maxPerArtist int, size int) []Suggestion {
+	limited := []Suggestion{}
+	counts := map[string]int{}
+
+	for _, s := range suggestions {
+		if len(limited) == size {
+			break
+		}
+
+		keys := artistidentity.GetKeys(s.Track.Artists)
+		isSaturated := false
+
+		for _, key := range keys {
+			if maxPerArtist > 0 && counts[key] >= maxPerArtist {
+				isSaturated = true
+			}
+		}
+
+		if isSaturated {
+			continue
+		}
+
+		for _, key := range keys {
+			counts[key]++
+		}
+
+		limited = append(limited, s)
+	}
+
+	return limited
+}
+
 func GetTracks(suggestions []Suggestion) []spotify.FullTrack {
 	tracks := []spotify.FullTrack{}
 
@@ -190,3 +363,85 @@ func GetTracks(suggestions []Suggestion) []spotify.FullTrack {
 
 	return tracks
 }
+
+func getVia(s Suggestion) string {
+	if len(s.Via) == 0 {
+		return ""
+	}
+
+	return s.GetOrigin()
+}
+
+func createPrintableBreakdown(breakdown []scoring.Contribution) string {
+	output := ""
+
+	for _, contribution := range breakdown {
+		output += fmt.Sprintf(
+			"   %s raw %8.2f x %5.2f = %8.2f\n",
+			utils.FixedWidthString(contribution.Name, 30),
+			contribution.Raw,
+			contribution.Weight,
+			contribution.Contribution,
+		)
+	}
+
+	return output
+}
+
+func rank(suggestions []Suggestion, scorer scoring.Scorer) ([]Suggestion, error) {
+	candidates := []scoring.Candidate{}
+
+	for _, suggestion := range suggestions {
+		candidates = append(candidates, suggestion.GetCandidate())
+	}
+
+	if err := scorer.Prepare(candidates); err != nil {
+		return suggestions, err
+	}
+
+	for index, candidate := range candidates {
+		result := scorer.Score(candidate)
+
+		suggestions[index].Relevance = result.Total
+		suggestions[index].Breakdown = result.Contributions
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Relevance > suggestions[j].Relevance
+	})
+
+	return suggestions, nil
+}
+
+// seenTracks keeps track of candidates already handled across sources, mapping
+// them to their index in the suggestions or -1 when they were skipped.
+type seenTracks struct {
+	byKey map[string]int
+	byID  map[spotify.ID]int
+}
+
+func createSeenTracks() seenTracks {
+	return seenTracks{
+		byKey: map[string]int{},
+		byID:  map[spotify.ID]int{},
+	}
+}
+
+func (s seenTracks) find(track spotify.FullTrack) (int, bool) {
+	if index, exists := s.byID[track.ID]; exists {
+		return index, true
+	}
+
+	index, exists := s.byKey[fulltrack.GetKey(track)]
+
+	return index, exists
+}
+
+func (s seenTracks) skip(track spotify.FullTrack) {
+	s.add(track, -1)
+}
+
+func (s seenTracks) add(track spotify.FullTrack, index int) {
+	s.byID[track.ID] = index
+	s.byKey[fulltrack.GetKey(track)] = index
+}
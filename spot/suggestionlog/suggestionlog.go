@@ -0,0 +1,149 @@
+package suggestionlog
+
+import (
+	"time"
+
+	"github.com/zmb3/spotify"
+
+	"github.com/kristofferostlund/spot/spot/cache"
+	"github.com/kristofferostlund/spot/spot/config"
+	"github.com/kristofferostlund/spot/spot/libraryindex"
+	"github.com/kristofferostlund/spot/spot/scoring"
+	"github.com/kristofferostlund/spot/spot/spotifytrack/fulltrack"
+	"github.com/kristofferostlund/spot/spot/utils"
+)
+
+type Entry struct {
+	TrackID     spotify.ID
+	TrackKey    string
+	Name        string
+	Artists     string
+	Source      string
+	Relevance   float64
+	Breakdown   []scoring.Contribution
+	SuggestedAt time.Time
+	AcceptedAt  time.Time
+	AcceptedIn  string
+}
+
+type Log struct {
+	Entries []Entry
+}
+
+type HistoryItem struct {
+	Count           int
+	LastSuggestedAt time.Time
+}
+
+type History struct {
+	byKey map[string]HistoryItem
+	byID  map[spotify.ID]HistoryItem
+}
+
+func Read() (Log, error) {
+	log := Log{Entries: []Entry{}}
+
+	if err := cache.ReadCache(config.SuggestionLogFilename, &log); err != nil {
+		return log, err
+	}
+
+	return log, nil
+}
+
+func Write(log Log) error {
+	return cache.WriteCache(config.SuggestionLogFilename, log)
+}
+
+func CreateEntry(
+	track spotify.FullTrack,
+	source string,
+	relevance float64,
+	breakdown []scoring.Contribution,
+	suggestedAt time.Time,
+) Entry {
+	return Entry{
+		TrackID:     track.ID,
+		TrackKey:    fulltrack.GetKey(track),
+		Name:        track.Name,
+		Artists:     utils.JoinArtists(track.Artists, ", "),
+		Source:      source,
+		Relevance:   relevance,
+		Breakdown:   breakdown,
+		SuggestedAt: suggestedAt,
+	}
+}
+
+func (e Entry) IsAccepted() bool {
+	return !e.AcceptedAt.IsZero()
+}
+
+func (l *Log) Record(entries []Entry) {
+	l.Entries = append(l.Entries, entries...)
+}
+
+func (l *Log) MarkAccepted(library libraryindex.Index) []Entry {
+	accepted := []Entry{}
+
+	for index, entry := range l.Entries {
+		if entry.IsAccepted() {
+			continue
+		}
+
+		for _, occurrence := range library.FindByReference(entry.TrackID, entry.TrackKey) {
+			// Tracks already in the library before being suggested weren't accepted suggestions
+			if !occurrence.AddedAt.IsZero() && occurrence.AddedAt.Before(entry.SuggestedAt) {
+				continue
+			}
+
+			entry.AcceptedAt = occurrence.AddedAt
+			entry.AcceptedIn = occurrence.PlaylistName
+
+			if entry.AcceptedAt.IsZero() {
+				entry.AcceptedAt = time.Now()
+			}
+
+			l.Entries[index] = entry
+			accepted = append(accepted, entry)
+
+			break
+		}
+	}
+
+	return accepted
+}
+
+func (l Log) GetHistory(since time.Time) History {
+	history := History{
+		byKey: map[string]HistoryItem{},
+		byID:  map[spotify.ID]HistoryItem{},
+	}
+
+	for _, entry := range l.Entries {
+		if entry.SuggestedAt.Before(since) {
+			continue
+		}
+
+		history.byKey[entry.TrackKey] = addToHistoryItem(history.byKey[entry.TrackKey], entry)
+		history.byID[entry.TrackID] = addToHistoryItem(history.byID[entry.TrackID], entry)
+	}
+
+	return history
+}
+
+func (h History) Get(track spotify.FullTrack) HistoryItem {
+	if item, exists := h.byKey[fulltrack.GetKey(track)]; exists {
+		return item
+	}
+
+	return h.byID[track.ID]
+}
+
+func addToHistoryItem(item HistoryItem, entry Entry) HistoryItem {
+	item.Count++
+
+	if entry.SuggestedAt.After(item.LastSuggestedAt) {
+		item.LastSuggestedAt = entry.SuggestedAt
+	}
+
+	return item
+}
@@ -42,17 +42,20 @@ func ChunkIDs(ids []spotify.ID, chunkSize int) [][]spotify.ID {
 	return chunks
 }
 
+// FixedWidthString pads or truncates the input to length characters, counting
+// runes rather than bytes to not cut multi-byte characters in half.
 func FixedWidthString(input string, length int) string {
-	dots := "..."
+	dots := []rune("...")
+	runes := []rune(input)
 
-	if len(input) > length {
-		input = input[:length-len(dots)] + dots
+	if len(runes) > length {
+		runes = append(runes[:length-len(dots)], dots...)
 	}
 
 	output := ""
 	for i := 0; i < length; i++ {
-		if len(input) > i {
-			output += string(input[i])
+		if len(runes) > i {
+			output += string(runes[i])
 		} else {
 			output += " "
 		}
@@ -186,3 +189,32 @@ func AverageFloat(values []float64) float64 {
 
 	return total / float64(len(values))
 }
+
+func ParseSpotifyID(value string) spotify.ID {
+	value = strings.TrimSpace(value)
+
+	// Handles both URLs, e.g. https://open.spotify.com/artist/<id>?si=<...>,
+	// and URIs, e.g. spotify:artist:<id>
+	if index := strings.Index(value, "?"); index != -1 {
+		value = value[:index]
+	}
+
+	separators := func(r rune) bool { return r == '/' || r == ':' }
+	parts := strings.FieldsFunc(value, separators)
+
+	if len(parts) == 0 {
+		return spotify.ID("")
+	}
+
+	return spotify.ID(parts[len(parts)-1])
+}
+
+func ContainsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}